@@ -13,7 +13,7 @@ renderMarkdown2Ansi renders markdown to ansi.
 */
 func renderMarkdown2Ansi(md string) string {
 	terminalWidth, _, _ := term.GetSize(int(os.Stdout.Fd()))
-	terminalData := markdown.Render(md, terminalWidth, 0)
+	terminalData := markdown.Render(replaceAttachmentReferences(md), terminalWidth, 0)
 
 	// replace ANSI colors in terminal data
 	terminalDataModified := string(terminalData)
@@ -0,0 +1,71 @@
+// live view: shows prompt/response pairs received from gemini-prompt via server-sent events
+(function() {
+  const entries = document.getElementById('live-entries');
+  const view = document.getElementById('live-view');
+  const status = document.getElementById('live-status');
+
+  // render code blocks and mermaid diagrams of (completed) entry
+  function decorate(section) {
+    if (typeof hljs !== 'undefined') {
+      section.querySelectorAll('pre > code:not(.mermaid)').forEach(block => hljs.highlightElement(block));
+    }
+    if (typeof mermaid !== 'undefined') {
+      mermaid.run({ nodes: section.querySelectorAll('.mermaid') });
+    }
+  }
+
+  // create or update entry (section in view and link in navigation)
+  function updateEntry(entry) {
+    let section = document.getElementById('entry-' + entry.id);
+    if (!section) {
+      section = document.createElement('section');
+      section.id = 'entry-' + entry.id;
+      view.appendChild(section);
+    }
+    section.innerHTML = entry.html;
+    if (!entry.done) {
+      const stream = document.createElement('pre');
+      stream.className = 'live-stream';
+      section.appendChild(stream);
+    } else {
+      decorate(section);
+    }
+
+    let link = document.getElementById('link-' + entry.id);
+    if (!link) {
+      link = document.createElement('div');
+      link.id = 'link-' + entry.id;
+      entries.appendChild(link);
+    }
+    link.innerHTML = '';
+    const anchor = document.createElement('a');
+    anchor.href = '#entry-' + entry.id;
+    anchor.textContent = entry.id + ': ' + entry.title;
+    link.appendChild(anchor);
+    if (entry.history) {
+      const history = document.createElement('a');
+      history.href = entry.history;
+      history.target = '_blank';
+      history.textContent = ' (history)';
+      link.appendChild(history);
+    }
+
+    section.scrollIntoView({ behavior: 'smooth', block: 'start' });
+  }
+
+  const source = new EventSource('/events');
+
+  source.addEventListener('entry', event => updateEntry(JSON.parse(event.data)));
+
+  source.addEventListener('status', event => {
+    status.textContent = JSON.parse(event.data);
+  });
+
+  source.addEventListener('chunk', event => {
+    const chunk = JSON.parse(event.data);
+    const stream = document.querySelector('#entry-' + chunk.id + ' .live-stream');
+    if (stream) {
+      stream.textContent += chunk.text;
+    }
+  });
+})();
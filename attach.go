@@ -0,0 +1,294 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/google/generative-ai-go/genai"
+)
+
+// protects uploadedFiles (attachment set is changed by main loop and read by localhost handlers)
+var uploadedFilesMu sync.Mutex
+
+/*
+attachFiles uploads files, directories or globs and adds them to attachment set (used by all following prompts).
+*/
+func attachFiles(ctx context.Context, client *genai.Client, geminiModel *genai.GenerativeModel, entries []string) {
+	filenames, skippedFiles := resolveUploadFiles(entries)
+	if len(skippedFiles) > 0 {
+		fmt.Printf("%d %s skipped (gitignore, filters)\n", len(skippedFiles), pluralize(len(skippedFiles), "file"))
+	}
+
+	// files already attached are not uploaded again
+	newFilenames := []string{}
+	for _, filename := range filenames {
+		if findAttachedFile(filename) >= 0 {
+			fmt.Printf("file [%s] already attached\n", filename)
+			continue
+		}
+		newFilenames = append(newFilenames, filename)
+	}
+	if len(newFilenames) == 0 {
+		return
+	}
+
+	files, inlineFiles, err := prepareFiles(ctx, client, newFilenames)
+	if err != nil {
+		fmt.Printf("error [%v] uploading files\n", err)
+		return
+	}
+	uploadedFilesMu.Lock()
+	uploadedFiles = append(uploadedFiles, files...)
+	inlinedFiles = append(inlinedFiles, inlineFiles...)
+	uploadedFilesMu.Unlock()
+	watchFiles(newFilenames)
+	attached := len(files) + len(inlineFiles)
+	fmt.Printf("%d %s attached.\n", attached, pluralize(attached, "file"))
+
+	updateContextCache(ctx, client, geminiModel)
+	saveAttachmentSet(geminiModel)
+}
+
+/*
+detachFiles removes files (given by number, name or pattern) from attachment set.
+Remote files are deleted unless they are still used by a chat session.
+*/
+func detachFiles(ctx context.Context, client *genai.Client, geminiModel *genai.GenerativeModel, selectors []string) {
+	detached := []*genai.File{}
+	remaining := []*genai.File{}
+	for i, uploadedFile := range uploadedFiles {
+		if matchAttachedFile(selectors, i+1, uploadedFile.DisplayName, uploadedFile.Name) {
+			detached = append(detached, uploadedFile)
+		} else {
+			remaining = append(remaining, uploadedFile)
+		}
+	}
+
+	// inlined files are numbered after uploaded files
+	detachedInline := []*InlineFile{}
+	remainingInline := []*InlineFile{}
+	for i, inlinedFile := range inlinedFiles {
+		if matchAttachedFile(selectors, len(uploadedFiles)+i+1, inlinedFile.Filename, "") {
+			detachedInline = append(detachedInline, inlinedFile)
+		} else {
+			remainingInline = append(remainingInline, inlinedFile)
+		}
+	}
+
+	if len(detached) == 0 && len(detachedInline) == 0 {
+		fmt.Printf("error: no attached file matches [%s]\n", strings.Join(selectors, " "))
+		return
+	}
+	uploadedFilesMu.Lock()
+	uploadedFiles = remaining
+	inlinedFiles = remainingInline
+	uploadedFilesMu.Unlock()
+
+	for _, inlinedFile := range detachedInline {
+		fmt.Printf("file [%s] detached (inline)\n", inlinedFile.Filename)
+	}
+
+	deleted := map[string]bool{}
+	for _, file := range detached {
+		if deleted[file.Name] {
+			fmt.Printf("file [%s] detached (shared remote file deleted)\n", file.DisplayName)
+			continue
+		}
+		if user, inUse := remoteFileUser(file); inUse {
+			fmt.Printf("file [%s] detached (remote file kept, still used by %s)\n", file.DisplayName, user)
+			continue
+		}
+		deleted[file.Name] = true
+		err := client.DeleteFile(ctx, file.Name)
+		if err != nil {
+			fmt.Printf("error [%v] deleting remote file [%s]\n", err, file.DisplayName)
+		} else {
+			fmt.Printf("file [%s] detached (remote file deleted)\n", file.DisplayName)
+		}
+		if uploadCache != nil {
+			uploadCache.remove(file.Name)
+		}
+	}
+	if uploadCache != nil {
+		uploadCache.save()
+	}
+
+	updateContextCache(ctx, client, geminiModel)
+	saveAttachmentSet(geminiModel)
+}
+
+/*
+matchAttachedFile checks if attached file is selected by number (as listed), local filename, remote name or pattern.
+*/
+func matchAttachedFile(selectors []string, number int, filename, remoteName string) bool {
+	for _, selector := range selectors {
+		if selector == strconv.Itoa(number) || selector == filename || (remoteName != "" && selector == remoteName) {
+			return true
+		}
+		if matched, err := path.Match(filepath.ToSlash(selector), filepath.ToSlash(filename)); err == nil && matched {
+			return true
+		}
+	}
+	return false
+}
+
+/*
+findAttachedFile finds attached file by local filename (-1 = not attached, converted documents by converted filename).
+*/
+func findAttachedFile(filename string) int {
+	if _, ok := findDocumentConverter(filename); ok {
+		filename = convertedFilename(filename)
+	}
+	for i, uploadedFile := range uploadedFiles {
+		if filepath.Clean(uploadedFile.DisplayName) == filepath.Clean(filename) {
+			return i
+		}
+	}
+	for i, inlinedFile := range inlinedFiles {
+		if filepath.Clean(inlinedFile.Filename) == filepath.Clean(filename) {
+			return len(uploadedFiles) + i
+		}
+	}
+	return -1
+}
+
+/*
+remoteFileUser checks if remote file is used by another attached file, current chat history or another saved
+chat session. Attached files with same content share one remote file (upload cache).
+*/
+func remoteFileUser(file *genai.File) (string, bool) {
+	if user, ok := remoteFileAttachment(file); ok {
+		return "attached file " + user, true
+	}
+	if chatSession != nil && isFileInChatHistory(file.URI) {
+		return "current chat", true
+	}
+	if !progConfig.ChatMode {
+		return "", false
+	}
+
+	pathnames, err := filepath.Glob(filepath.Join(progConfig.ChatSessionDirectory, "*.json"))
+	if err != nil {
+		return "", false
+	}
+	for _, pathname := range pathnames {
+		name := strings.TrimSuffix(filepath.Base(pathname), ".json")
+		if name == chatSessionName {
+			continue
+		}
+		sessionData, err := loadChatSession(name)
+		if err != nil {
+			continue
+		}
+		for _, sessionFile := range sessionData.Files {
+			if sessionFile.Name == file.Name || sessionFile.URI == file.URI {
+				return "chat session " + name, true
+			}
+		}
+	}
+	return "", false
+}
+
+/*
+remoteFileAttachment checks if remote file is referenced by another attached file or file of chat prompt.
+Returns local filename of attached file.
+*/
+func remoteFileAttachment(file *genai.File) (string, bool) {
+	uploadedFilesMu.Lock()
+	defer uploadedFilesMu.Unlock()
+	for _, uploadedFile := range append(append([]*genai.File{}, uploadedFiles...), promptUploadedFiles...) {
+		if uploadedFile != file && uploadedFile.Name == file.Name {
+			return uploadedFile.DisplayName, true
+		}
+	}
+	return "", false
+}
+
+/*
+saveAttachmentSet saves changed attachment set in current chat session.
+*/
+func saveAttachmentSet(geminiModel *genai.GenerativeModel) {
+	if chatSession == nil {
+		return
+	}
+	err := saveChatSession(geminiModel)
+	if err != nil {
+		fmt.Printf("error [%v] saving chat session\n", err)
+	}
+}
+
+/*
+formatAttachments formats list of attached files (numbered, as used by '/detach').
+*/
+func formatAttachments() string {
+	uploadedFilesMu.Lock()
+	defer uploadedFilesMu.Unlock()
+
+	var list strings.Builder
+	list.WriteString("\nAttached files:\n")
+	if len(uploadedFiles) == 0 && len(inlinedFiles) == 0 {
+		list.WriteString("  none\n")
+	}
+	for i, uploadedFile := range uploadedFiles {
+		list.WriteString(fmt.Sprintf("  %3d  %-10s  %12s  %-28.28s  %-24s  %s\n", i+1, uploadedFile.State.String(),
+			fmt.Sprintf("%.1f KiB", float64(uploadedFile.SizeBytes)/1024.0), uploadedFile.MIMEType,
+			formatExpiration(uploadedFile.ExpirationTime), uploadedFile.DisplayName))
+	}
+	for i, inlinedFile := range inlinedFiles {
+		list.WriteString(fmt.Sprintf("  %3d  %-10s  %12s  %-28.28s  %-24s  %s\n", len(uploadedFiles)+i+1, "INLINE",
+			fmt.Sprintf("%.1f KiB", float64(inlinedFile.Size)/1024.0), inlinedFile.MIMEType, "-", inlinedFile.Filename))
+	}
+	list.WriteString("\n")
+	return list.String()
+}
+
+/*
+handleAttachmentCommand returns handler for localhost attachment endpoints (/attach, /detach, /attachments).
+Attach and detach requests (one entry per line) are queued as commands, list requests are answered directly.
+Cross-origin requests (e.g. from web pages) are refused, no CORS headers are sent.
+*/
+func handleAttachmentCommand(promptChannel chan string, command string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if isCrossOriginRequest(r) {
+			http.Error(w, "cross-origin request not allowed", http.StatusForbidden)
+			fmt.Printf("error: cross-origin request [%s %s] from [%s] refused\n", r.Method, r.URL.Path, r.Header.Get("Origin"))
+			return
+		}
+
+		if command == "/attachments" {
+			fmt.Fprint(w, formatAttachments())
+			return
+		}
+
+		body, err := io.ReadAll(r.Body)
+		if err != nil {
+			http.Error(w, "error reading request body", http.StatusBadRequest)
+			fmt.Printf("error [%v] reading request body\n", err)
+			return
+		}
+		defer r.Body.Close()
+
+		arguments := strings.Fields(string(body))
+		if len(arguments) == 0 {
+			http.Error(w, "files missing", http.StatusBadRequest)
+			return
+		}
+		prompt := command + " " + strings.Join(arguments, " ")
+		err = checkRemotePrompt(prompt)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusForbidden)
+			fmt.Printf("error [%v], command refused\n", err)
+			return
+		}
+		submitPrompt(promptChannel, prompt)
+
+		fmt.Fprintln(w, "command received")
+	}
+}
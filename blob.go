@@ -0,0 +1,154 @@
+package main
+
+import (
+	"fmt"
+	"mime"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/google/generative-ai-go/genai"
+)
+
+// ResponseBlobFile represents inline data part of response (e.g. generated image) saved as file
+type ResponseBlobFile struct {
+	Link     string // relative to working and history directories (e.g. 'attachments/...png')
+	MIMEType string
+	Size     int
+}
+
+// inline data parts of current response saved as files (key: candidate index, part index)
+var responseBlobFiles = map[[2]int]ResponseBlobFile{}
+
+// preferred filename extensions of common MIME types (others are taken from mime package)
+var blobExtensions = map[string]string{
+	"image/jpeg":      ".jpg",
+	"image/png":       ".png",
+	"image/webp":      ".webp",
+	"audio/mpeg":      ".mp3",
+	"audio/wav":       ".wav",
+	"audio/l16":       ".pcm",
+	"video/mp4":       ".mp4",
+	"application/pdf": ".pdf",
+	"text/plain":      ".txt",
+}
+
+// media links (markdown title 'audio' or 'video') rendered as html media elements
+var mediaLinkPattern = regexp.MustCompile(`<a href="([^"]*)" title="(audio|video)">(.*?)</a>`)
+
+// attachment references in markdown replaced by placeholder in ansi output
+var attachmentReferencePattern = regexp.MustCompile(`!?\[((?:Image|Audio|Video|File) \([^\]]*\))\]\(<([^>]*)>(?: "(?:audio|video)")?\)`)
+
+/*
+blobExtension gets filename extension for MIME type of inline data (e.g. '.png').
+*/
+func blobExtension(mimeType string) string {
+	mediaType, _, err := mime.ParseMediaType(mimeType)
+	if err != nil {
+		return ".bin"
+	}
+	if extension, ok := blobExtensions[mediaType]; ok {
+		return extension
+	}
+	extensions, err := mime.ExtensionsByType(mediaType)
+	if err != nil || len(extensions) == 0 {
+		return ".bin"
+	}
+	return extensions[0]
+}
+
+/*
+saveResponseBlobs writes inline data parts of response into attachment directories (working directory,
+markdown and html history directories). Files are named like history entry with part number.
+*/
+func saveResponseBlobs(now time.Time, prompt, workingDirectory string, resp *genai.GenerateContentResponse) {
+	responseBlobFiles = map[[2]int]ResponseBlobFile{}
+	if !progConfig.ResponseAttachments || resp == nil {
+		return
+	}
+
+	directories := []string{filepath.Join(workingDirectory, progConfig.ResponseAttachmentsDirectory)}
+	if progConfig.MarkdownHistory {
+		directories = append(directories, filepath.Join(workingDirectory, progConfig.MarkdownHistoryDirectory, progConfig.ResponseAttachmentsDirectory))
+	}
+	if progConfig.HTMLHistory {
+		directories = append(directories, filepath.Join(workingDirectory, progConfig.HTMLHistoryDirectory, progConfig.ResponseAttachmentsDirectory))
+	}
+
+	number := 0
+	for i, candidate := range resp.Candidates {
+		if candidate.Content == nil {
+			continue
+		}
+		for j, part := range candidate.Content.Parts {
+			blob, ok := part.(genai.Blob)
+			if !ok {
+				continue
+			}
+			number++
+			extension := fmt.Sprintf("%d%s", number, blobExtension(blob.MIMEType))
+			filename := buildDestinationFilename(now, prompt, extension)
+
+			saved := true
+			for _, directory := range directories {
+				err := os.MkdirAll(directory, 0750)
+				if err != nil {
+					fmt.Printf("error [%v] at os.MkdirAll()\n", err)
+					saved = false
+					continue
+				}
+				err = os.WriteFile(filepath.Join(directory, filename), blob.Data, 0644)
+				if err != nil {
+					fmt.Printf("error [%v] at os.WriteFile()\n", err)
+					saved = false
+				}
+			}
+			if saved {
+				responseBlobFiles[[2]int{i, j}] = ResponseBlobFile{
+					Link:     path.Join(filepath.ToSlash(progConfig.ResponseAttachmentsDirectory), filename),
+					MIMEType: blob.MIMEType,
+					Size:     len(blob.Data),
+				}
+			}
+		}
+	}
+}
+
+/*
+formatResponseBlob formats inline data part of response as markdown reference to saved file
+(image, audio or video element in html, placeholder in ansi).
+*/
+func formatResponseBlob(blob genai.Blob, candidate, part int) string {
+	blobFile, ok := responseBlobFiles[[2]int{candidate, part}]
+	if !ok {
+		return fmt.Sprintf("Inline Data: MIME=%s, %.1f KiB (not saved)\n", blob.MIMEType, float64(len(blob.Data))/1024.0)
+	}
+
+	description := fmt.Sprintf("(%s, %.1f KiB)", blobFile.MIMEType, float64(blobFile.Size)/1024.0)
+	switch strings.SplitN(blobFile.MIMEType, "/", 2)[0] {
+	case "image":
+		return fmt.Sprintf("![Image %s](<%s>)\n", description, blobFile.Link)
+	case "audio":
+		return fmt.Sprintf("[Audio %s](<%s> \"audio\")\n", description, blobFile.Link)
+	case "video":
+		return fmt.Sprintf("[Video %s](<%s> \"video\")\n", description, blobFile.Link)
+	}
+	return fmt.Sprintf("[File %s](<%s>)\n", description, blobFile.Link)
+}
+
+/*
+embedMediaLinks renders links to saved audio and video files as html media elements.
+*/
+func embedMediaLinks(html string) string {
+	return mediaLinkPattern.ReplaceAllString(html, `<$2 controls src="$1" title="$3"></$2>`)
+}
+
+/*
+replaceAttachmentReferences replaces references to saved files in markdown by placeholder with file path (ansi output).
+*/
+func replaceAttachmentReferences(md string) string {
+	return attachmentReferencePattern.ReplaceAllString(md, "`[$1: $2]`")
+}
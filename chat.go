@@ -0,0 +1,84 @@
+package main
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/google/generative-ai-go/genai"
+)
+
+// current chat session (nil = chat mode disabled, each prompt is self-contained)
+var chatSession *genai.ChatSession
+
+// markdown transcript of current chat (one prompt/response pair per completed turn)
+var chatTranscript []string
+
+/*
+startNewChat starts a new chat (conversation) with given AI model.
+*/
+func startNewChat(geminiModel *genai.GenerativeModel) {
+	chatSession = geminiModel.StartChat()
+	chatTranscript = []string{}
+}
+
+/*
+sendChatMessage sends prompt parts as next message of current chat.
+*/
+func sendChatMessage(ctx context.Context, promptParts []genai.Part) (*genai.GenerateContentResponse, error) {
+	historyLength := len(chatSession.History)
+
+	resp, err := chatSession.SendMessage(ctx, promptParts...)
+	if err != nil {
+		chatSession.History = chatSession.History[:historyLength]
+	}
+	removeUnansweredChatPrompt(historyLength)
+
+	return resp, err
+}
+
+/*
+removeUnansweredChatPrompt removes prompt without response from chat history (keeps user/model roles alternating).
+*/
+func removeUnansweredChatPrompt(historyLength int) {
+	if len(chatSession.History) == historyLength+1 {
+		chatSession.History = chatSession.History[:historyLength]
+	}
+}
+
+/*
+isFileInChatHistory checks if remote file is already referenced by current chat history.
+*/
+func isFileInChatHistory(uri string) bool {
+	for _, content := range chatSession.History {
+		for _, part := range content.Parts {
+			if fileData, ok := part.(genai.FileData); ok && fileData.URI == uri {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+/*
+countChatTurns counts prompt/response pairs (turns) of current chat.
+*/
+func countChatTurns() int {
+	turns := 0
+	for _, content := range chatSession.History {
+		if content.Role == "model" {
+			turns++
+		}
+	}
+	return turns
+}
+
+/*
+printChatInfo prints information about current chat to the console.
+*/
+func printChatInfo() {
+	fmt.Printf("\nChat:\n")
+	fmt.Printf("  Session : %s\n", chatSessionName)
+	fmt.Printf("  Created : %s\n", chatSessionCreated.Format("2006-01-02 15:04:05"))
+	fmt.Printf("  Turns   : %d\n", countChatTurns())
+	fmt.Printf("\n")
+}
@@ -0,0 +1,46 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/google/generative-ai-go/genai"
+)
+
+// markdown language names of code executed by AI model
+var executableCodeLanguages = map[genai.ExecutableCodeLanguage]string{
+	genai.ExecutableCodePython: "python",
+}
+
+// readable outcomes of code execution
+var codeExecutionOutcomes = map[genai.CodeExecutionResultOutcome]string{
+	genai.CodeExecutionResultOutcomeOK:               "ok",
+	genai.CodeExecutionResultOutcomeFailed:           "failed",
+	genai.CodeExecutionResultOutcomeDeadlineExceeded: "deadline exceeded",
+}
+
+/*
+formatExecutableCode formats code generated (and executed) by AI model as highlighted markdown code block.
+*/
+func formatExecutableCode(code *genai.ExecutableCode) string {
+	language, ok := executableCodeLanguages[code.Language]
+	if !ok {
+		language = "plaintext"
+	}
+	return fmt.Sprintf("Executed Code (%s):\n\n```%s\n%s\n```\n", language, language, strings.TrimRight(code.Code, "\n"))
+}
+
+/*
+formatCodeExecutionResult formats outcome and output (stdout or error description) of code execution as markdown.
+*/
+func formatCodeExecutionResult(result *genai.CodeExecutionResult) string {
+	outcome, ok := codeExecutionOutcomes[result.Outcome]
+	if !ok {
+		outcome = "unknown"
+	}
+	output := strings.TrimRight(result.Output, "\n")
+	if output == "" {
+		output = "(no output)"
+	}
+	return fmt.Sprintf("Code Execution Result (%s):\n\n```plaintext\n%s\n```\n", outcome, output)
+}
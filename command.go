@@ -0,0 +1,153 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/generative-ai-go/genai"
+)
+
+// Command describes a program command given instead of a prompt (e.g. '/new')
+type Command struct {
+	Name        string
+	Arguments   string
+	Description string
+}
+
+// commands contains all supported program commands
+var commands = []Command{
+	{Name: "/help", Description: "show list of commands"},
+	{Name: "/new", Description: "start new chat (conversation)"},
+	{Name: "/chat", Description: "show information about current chat"},
+	{Name: "/sessions", Description: "show all saved chat sessions"},
+	{Name: "/resume", Arguments: "name", Description: "resume saved chat session"},
+	{Name: "/fork", Arguments: "turn [name]", Description: "continue with new chat session forked from given turn"},
+	{Name: "/attach", Arguments: "files|dirs|globs", Description: "upload files and attach them to all following prompts"},
+	{Name: "/detach", Arguments: "numbers|names|globs", Description: "detach files (remote files are deleted unless used by a chat session)"},
+	{Name: "/attachments", Description: "show list of attached files"},
+	{Name: "/sync", Description: "re-upload attached files which have been changed locally or expire soon"},
+}
+
+/*
+isCommand checks if prompt is a program command.
+*/
+func isCommand(prompt string) bool {
+	fields := strings.Fields(prompt)
+	if len(fields) == 0 {
+		return false
+	}
+	for _, command := range commands {
+		if fields[0] == command.Name {
+			return true
+		}
+	}
+	return false
+}
+
+/*
+handleCommand handles program command given via any input channel.
+*/
+func handleCommand(ctx context.Context, client *genai.Client, geminiModel *genai.GenerativeModel, prompt string) {
+	fields := strings.Fields(prompt)
+
+	switch fields[0] {
+	case "/help":
+		printCommands()
+	case "/new":
+		if chatSession == nil {
+			fmt.Printf("error: chat mode not enabled\n")
+			return
+		}
+		startNewChat(geminiModel)
+		chatSessionCreated = time.Now()
+		chatSessionName = buildChatSessionName(chatSessionCreated)
+		fmt.Printf("New chat [%s] started.\n", chatSessionName)
+	case "/chat":
+		if chatSession == nil {
+			fmt.Printf("error: chat mode not enabled\n")
+			return
+		}
+		printChatInfo()
+	case "/sessions":
+		listChatSessions()
+	case "/resume":
+		if chatSession == nil {
+			fmt.Printf("error: chat mode not enabled\n")
+			return
+		}
+		if len(fields) != 2 {
+			fmt.Printf("error: name of chat session required\n")
+			return
+		}
+		if !validChatSessionName.MatchString(fields[1]) || !chatSessionExists(fields[1]) {
+			fmt.Printf("error: chat session [%s] not found\n", fields[1])
+			return
+		}
+		err := resumeChatSession(ctx, client, geminiModel, fields[1])
+		if err != nil {
+			fmt.Printf("error [%v] resuming chat session\n", err)
+			return
+		}
+		fmt.Printf("Chat session [%s] resumed.\n", chatSessionName)
+		printChatInfo()
+	case "/fork":
+		if chatSession == nil {
+			fmt.Printf("error: chat mode not enabled\n")
+			return
+		}
+		if len(fields) < 2 || len(fields) > 3 {
+			fmt.Printf("error: turn (and optional name) of chat session required\n")
+			return
+		}
+		turn, err := strconv.Atoi(fields[1])
+		if err != nil {
+			fmt.Printf("error: invalid turn [%s]\n", fields[1])
+			return
+		}
+		name := buildChatSessionName(time.Now())
+		if len(fields) == 3 {
+			name = fields[2]
+		}
+		if !validChatSessionName.MatchString(name) || chatSessionExists(name) {
+			fmt.Printf("error: invalid or already existing chat session name [%s]\n", name)
+			return
+		}
+		err = forkChatSession(geminiModel, turn, name)
+		if err != nil {
+			fmt.Printf("error [%v] forking chat session\n", err)
+			return
+		}
+		fmt.Printf("Chat session [%s] forked at turn %d.\n", chatSessionName, turn)
+		printChatInfo()
+	case "/attach":
+		if len(fields) < 2 {
+			fmt.Printf("error: files, directories or globs to attach required\n")
+			return
+		}
+		attachFiles(ctx, client, geminiModel, fields[1:])
+	case "/detach":
+		if len(fields) < 2 {
+			fmt.Printf("error: numbers, names or globs of files to detach required\n")
+			return
+		}
+		detachFiles(ctx, client, geminiModel, fields[1:])
+	case "/attachments":
+		fmt.Print(formatAttachments())
+	case "/sync":
+		syncAttachedFiles(ctx, client, geminiModel)
+	}
+}
+
+/*
+printCommands prints list of supported program commands.
+*/
+func printCommands() {
+	fmt.Printf("\nCommands:\n")
+	for _, command := range commands {
+		fmt.Printf("  %-24s : %s\n", strings.TrimSpace(command.Name+" "+command.Arguments), command.Description)
+	}
+	fmt.Printf("\n")
+}
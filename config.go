@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"os"
 	"runtime"
+	"slices"
 	"strings"
 
 	"gopkg.in/yaml.v3"
@@ -11,15 +12,61 @@ import (
 
 // ProgConfig represents program configuration
 type ProgConfig struct {
-	GeminiAPIKey                    string  `yaml:"GeminiAPIKey"`
-	GeminiAiModel                   string  `yaml:"GeminiAiModel"`
-	GeminiCandidateCount            int32   `yaml:"GeminiCandidateCount"`
-	GeminiMaxOutputTokens           int32   `yaml:"GeminiMaxOutputTokens"`
-	GeminiTemperature               float32 `yaml:"GeminiTemperature"`
-	GeminiTopP                      float32 `yaml:"GeminiTopP"`
-	GeminiTopK                      int32   `yaml:"GeminiTopK"`
-	GeminiSystemInstruction         string  `yaml:"GeminiSystemInstruction"`
-	GeminiMaxWaitTimeFileProcessing int     `yaml:"GeminiMaxWaitTimeFileProcessing"`
+	GeminiAPIKey                    string   `yaml:"GeminiAPIKey"`
+	GeminiAiModel                   string   `yaml:"GeminiAiModel"`
+	GeminiCandidateCount            int32    `yaml:"GeminiCandidateCount"`
+	GeminiMaxOutputTokens           int32    `yaml:"GeminiMaxOutputTokens"`
+	GeminiTemperature               float32  `yaml:"GeminiTemperature"`
+	GeminiTopP                      float32  `yaml:"GeminiTopP"`
+	GeminiTopK                      int32    `yaml:"GeminiTopK"`
+	GeminiSystemInstruction         string   `yaml:"GeminiSystemInstruction"`
+	GeminiMaxWaitTimeFileProcessing int      `yaml:"GeminiMaxWaitTimeFileProcessing"`
+	GeminiUploadConcurrency         int      `yaml:"GeminiUploadConcurrency"`
+	GeminiStreamResponse            bool     `yaml:"GeminiStreamResponse"`
+	GeminiTokenLimitPolicy          string   `yaml:"GeminiTokenLimitPolicy"`
+	GeminiTokenLimitThreshold       int      `yaml:"GeminiTokenLimitThreshold"`
+	GeminiFallbackModels            []string `yaml:"GeminiFallbackModels"`
+	GeminiFallbackErrorClasses      []string `yaml:"GeminiFallbackErrorClasses"`
+	//
+	UploadRespectGitignore    bool              `yaml:"UploadRespectGitignore"`
+	UploadIncludePatterns     []string          `yaml:"UploadIncludePatterns"`
+	UploadExcludePatterns     []string          `yaml:"UploadExcludePatterns"`
+	UploadMaxFileSize         int64             `yaml:"UploadMaxFileSize"`
+	UploadSkipBinaryFiles     bool              `yaml:"UploadSkipBinaryFiles"`
+	UploadCache               bool              `yaml:"UploadCache"`
+	UploadCacheFile           string            `yaml:"UploadCacheFile"`
+	UploadInlineMaxSize       int64             `yaml:"UploadInlineMaxSize"`
+	UploadInlineTextFiles     bool              `yaml:"UploadInlineTextFiles"`
+	UploadInlineTextMaxSize   int64             `yaml:"UploadInlineTextMaxSize"`
+	UploadMimeTypes           map[string]string `yaml:"UploadMimeTypes"`
+	UploadKeepFiles           bool              `yaml:"UploadKeepFiles"`
+	UploadConvertDocuments    bool              `yaml:"UploadConvertDocuments"`
+	UploadConvertDirectory    string            `yaml:"UploadConvertDirectory"`
+	UploadWatchFiles          bool              `yaml:"UploadWatchFiles"`
+	UploadWatchInterval       int               `yaml:"UploadWatchInterval"`
+	UploadRefreshBeforeExpiry int               `yaml:"UploadRefreshBeforeExpiry"`
+	//
+	ContextCache     bool `yaml:"ContextCache"`
+	ContextCacheTTL  int  `yaml:"ContextCacheTTL"`
+	ContextCacheKeep bool `yaml:"ContextCacheKeep"`
+	//
+	FunctionTools         []FunctionTool `yaml:"FunctionTools"`
+	FunctionCallMaxRounds int            `yaml:"FunctionCallMaxRounds"`
+	FunctionCallTimeout   int            `yaml:"FunctionCallTimeout"`
+	CodeExecution         bool           `yaml:"CodeExecution"`
+	//
+	SafetySettings map[string]string `yaml:"SafetySettings"`
+	//
+	ResponseSchemaFile   string `yaml:"ResponseSchemaFile"`
+	JSONHistory          bool   `yaml:"JSONHistory"`
+	JSONHistoryDirectory string `yaml:"JSONHistoryDirectory"`
+	//
+	PromptDirectives              bool `yaml:"PromptDirectives"`
+	PromptDirectiveCommands       bool `yaml:"PromptDirectiveCommands"`
+	PromptDirectiveCommandTimeout int  `yaml:"PromptDirectiveCommandTimeout"`
+	//
+	ChatMode             bool   `yaml:"ChatMode"`
+	ChatSessionDirectory string `yaml:"ChatSessionDirectory"`
 	//
 	MarkdownPromptResponseFile       string `yaml:"MarkdownPromptResponseFile"`
 	MarkdownOutput                   bool   `yaml:"MarkdownOutput"`
@@ -46,6 +93,7 @@ type ProgConfig struct {
 	HTMLOutputApplicationLinux   string              `yaml:"HTMLOutputApplicationLinux"`
 	HTMLOutputApplicationWindows string              `yaml:"HTMLOutputApplicationWindows"`
 	HTMLOutputApplicationOther   string              `yaml:"HTMLOutputApplicationOther"`
+	HTMLLiveView                 bool                `yaml:"HTMLLiveView"`
 	HTMLHistory                  bool                `yaml:"HTMLHistory"`
 	HTMLHistoryDirectory         string              `yaml:"HTMLHistoryDirectory"`
 	HTMLMaxLengthTitle           int                 `yaml:"HTMLMaxLengthTitle"`
@@ -78,7 +126,24 @@ type ProgConfig struct {
 	HistoryFilenameExtensionMarkdown string `yaml:"HistoryFilenameExtensionMarkdown"`
 	HistoryFilenameExtensionAnsi     string `yaml:"HistoryFilenameExtensionAnsi"`
 	HistoryFilenameExtensionHTML     string `yaml:"HistoryFilenameExtensionHTML"`
+	HistoryFilenameExtensionJSON     string `yaml:"HistoryFilenameExtensionJSON"`
 	HistoryMaxFilenameLength         int    `yaml:"HistoryMaxFilenameLength"`
+	ResponseAttachments              bool   `yaml:"ResponseAttachments"`
+	ResponseAttachmentsDirectory     string `yaml:"ResponseAttachmentsDirectory"`
+	//
+	ExtractCodeBlocks    bool   `yaml:"ExtractCodeBlocks"`
+	ExtractCodeDirectory string `yaml:"ExtractCodeDirectory"`
+	//
+	UsageLedger     bool                  `yaml:"UsageLedger"`
+	UsageLedgerFile string                `yaml:"UsageLedgerFile"`
+	UsagePrices     map[string]UsagePrice `yaml:"UsagePrices"`
+	//
+	RateLimits map[string]RateLimit `yaml:"RateLimits"`
+	//
+	RetryMaxAttempts  int      `yaml:"RetryMaxAttempts"`
+	RetryInitialDelay float64  `yaml:"RetryInitialDelay"`
+	RetryMaxDelay     float64  `yaml:"RetryMaxDelay"`
+	RetryErrorClasses []string `yaml:"RetryErrorClasses"`
 	//
 	GeneralInternetProxy string `yaml:"GeneralInternetProxy"`
 }
@@ -111,6 +176,118 @@ func loadConfiguration(configFile string) error {
 	if progConfig.GeminiCandidateCount <= 0 {
 		return fmt.Errorf("empty GeminiCandidateCount not allowed")
 	}
+	progConfig.GeminiTokenLimitPolicy = strings.ToLower(progConfig.GeminiTokenLimitPolicy)
+	switch progConfig.GeminiTokenLimitPolicy {
+	case "":
+		progConfig.GeminiTokenLimitPolicy = "none"
+	case "none", "warn", "ask", "refuse":
+	default:
+		return fmt.Errorf("unsupported GeminiTokenLimitPolicy (not 'none', 'warn', 'ask' or 'refuse')")
+	}
+	if progConfig.GeminiTokenLimitThreshold < 0 || progConfig.GeminiTokenLimitThreshold > 100 {
+		return fmt.Errorf("GeminiTokenLimitThreshold must be between 0 and 100")
+	}
+	if len(progConfig.GeminiFallbackErrorClasses) == 0 {
+		progConfig.GeminiFallbackErrorClasses = []string{"quota", "overloaded", "notfound"}
+	}
+	for i, errorClass := range progConfig.GeminiFallbackErrorClasses {
+		progConfig.GeminiFallbackErrorClasses[i] = strings.ToLower(errorClass)
+		if !slices.Contains(fallbackErrorClasses, progConfig.GeminiFallbackErrorClasses[i]) {
+			return fmt.Errorf("unsupported GeminiFallbackErrorClasses entry [%s] (not '%s')", errorClass, strings.Join(fallbackErrorClasses, "', '"))
+		}
+	}
+
+	// upload
+	if progConfig.GeminiUploadConcurrency <= 0 {
+		progConfig.GeminiUploadConcurrency = 1
+	}
+	if progConfig.UploadMaxFileSize < 0 {
+		return fmt.Errorf("UploadMaxFileSize must not be negative")
+	}
+	if progConfig.UploadInlineMaxSize < 0 || progConfig.UploadInlineTextMaxSize < 0 {
+		return fmt.Errorf("UploadInlineMaxSize and UploadInlineTextMaxSize must not be negative")
+	}
+	mimeTypes := map[string]string{}
+	for extension, mimeType := range progConfig.UploadMimeTypes {
+		extension = strings.ToLower(extension)
+		if !strings.HasPrefix(extension, ".") {
+			extension = "." + extension
+		}
+		mimeTypes[extension] = mimeType
+	}
+	progConfig.UploadMimeTypes = mimeTypes
+	if progConfig.UploadCache && progConfig.UploadCacheFile == "" {
+		return fmt.Errorf("empty UploadCacheFile not allowed")
+	}
+	if progConfig.UploadConvertDocuments && progConfig.UploadConvertDirectory == "" {
+		return fmt.Errorf("empty UploadConvertDirectory not allowed")
+	}
+	if progConfig.UploadWatchInterval <= 0 {
+		progConfig.UploadWatchInterval = 10
+	}
+	if progConfig.UploadRefreshBeforeExpiry < 0 {
+		return fmt.Errorf("UploadRefreshBeforeExpiry must not be negative")
+	}
+
+	// context cache
+	if progConfig.ContextCacheTTL <= 0 {
+		progConfig.ContextCacheTTL = 60
+	}
+
+	// function calling
+	functionNames := map[string]bool{}
+	for _, function := range progConfig.FunctionTools {
+		if !functionNamePattern.MatchString(function.Name) {
+			return fmt.Errorf("invalid function name [%s] (a-z, A-Z, 0-9, '_', '-', max. 63 characters)", function.Name)
+		}
+		if functionNames[function.Name] {
+			return fmt.Errorf("function [%s] declared more than once", function.Name)
+		}
+		functionNames[function.Name] = true
+		if (function.Command == "") == (function.URL == "") {
+			return fmt.Errorf("function [%s] requires either Command or URL", function.Name)
+		}
+	}
+	if _, err := buildFunctionTool(); err != nil {
+		return err
+	}
+	if progConfig.FunctionCallMaxRounds <= 0 {
+		progConfig.FunctionCallMaxRounds = 10
+	}
+	if progConfig.FunctionCallTimeout <= 0 {
+		progConfig.FunctionCallTimeout = 60
+	}
+
+	// safety settings
+	if _, err := buildSafetySettings(); err != nil {
+		return err
+	}
+
+	// structured output
+	if progConfig.ResponseSchemaFile != "" {
+		responseSchema, err := loadResponseSchema(progConfig.ResponseSchemaFile)
+		if err != nil {
+			return err
+		}
+		defaultResponseSchema = responseSchema
+	}
+	if progConfig.JSONHistory && progConfig.JSONHistoryDirectory == "" {
+		return fmt.Errorf("empty JSONHistoryDirectory not allowed")
+	}
+	if progConfig.HistoryFilenameExtensionJSON == "" {
+		progConfig.HistoryFilenameExtensionJSON = "json"
+	}
+
+	// prompt directives
+	if progConfig.PromptDirectiveCommandTimeout <= 0 {
+		progConfig.PromptDirectiveCommandTimeout = 60
+	}
+
+	// chat
+	// default (chat mode can be enabled via command line, e.g. '-chat', '-session')
+	if progConfig.ChatSessionDirectory == "" {
+		progConfig.ChatSessionDirectory = "./chat-sessions"
+	}
 
 	// markdown
 	if progConfig.MarkdownPromptResponseFile == "" {
@@ -161,6 +338,9 @@ func loadConfiguration(configFile string) error {
 	if progConfig.HTMLHistory && progConfig.HTMLHistoryDirectory == "" {
 		return fmt.Errorf("empty HTMLHistoryDirectory not allowed")
 	}
+	if progConfig.HTMLLiveView && !progConfig.InputFromLocalhost {
+		return fmt.Errorf("HTMLLiveView requires InputFromLocalhost")
+	}
 
 	// input
 	if progConfig.InputFromFile && progConfig.InputFile == "" {
@@ -206,6 +386,35 @@ func loadConfiguration(configFile string) error {
 	if progConfig.HistoryMaxFilenameLength > 255 {
 		return fmt.Errorf("max length of history filename show not be greater than 255")
 	}
+	if progConfig.ResponseAttachments && progConfig.ResponseAttachmentsDirectory == "" {
+		return fmt.Errorf("empty ResponseAttachmentsDirectory not allowed")
+	}
+
+	// code extraction
+	// default (option '-extract' writes code blocks regardless of ExtractCodeBlocks)
+	if progConfig.ExtractCodeDirectory == "" {
+		progConfig.ExtractCodeDirectory = "./extracted-code"
+	}
+
+	// usage
+	// default (usage report '-usage' reads ledger regardless of UsageLedger)
+	if progConfig.UsageLedgerFile == "" {
+		progConfig.UsageLedgerFile = "usage-ledger.jsonl"
+	}
+
+	// retry
+	if progConfig.RetryMaxAttempts < 1 {
+		progConfig.RetryMaxAttempts = 1
+	}
+	if progConfig.RetryInitialDelay < 0 || progConfig.RetryMaxDelay < progConfig.RetryInitialDelay {
+		return fmt.Errorf("RetryInitialDelay must not be negative and not greater than RetryMaxDelay")
+	}
+	for i, errorClass := range progConfig.RetryErrorClasses {
+		progConfig.RetryErrorClasses[i] = strings.ToLower(errorClass)
+		if !slices.Contains(retryableErrorClasses, progConfig.RetryErrorClasses[i]) {
+			return fmt.Errorf("unsupported RetryErrorClasses entry [%s] (not '%s')", errorClass, strings.Join(retryableErrorClasses, "', '"))
+		}
+	}
 
 	// get api-key (password)
 	progConfig.GeminiAPIKey, err = getPassword(progConfig.GeminiAPIKey)
@@ -264,6 +473,9 @@ func showConfiguration() {
 	if progConfig.HTMLHistory {
 		fmt.Printf("  HTML     : %v\n", progConfig.HTMLHistoryDirectory)
 	}
+	if progConfig.JSONHistory {
+		fmt.Printf("  JSON     : %v\n", progConfig.JSONHistoryDirectory)
+	}
 
 	fmt.Printf("\nOutput:\n")
 	if progConfig.AnsiOutput {
@@ -273,7 +485,11 @@ func showConfiguration() {
 		fmt.Printf("  Markdown : execute application\n")
 	}
 	if progConfig.HTMLOutput {
-		fmt.Printf("  HTML     : execute application\n")
+		if progConfig.HTMLLiveView {
+			fmt.Printf("  HTML     : live view (%s)\n", liveViewURL())
+		} else {
+			fmt.Printf("  HTML     : execute application\n")
+		}
 	}
 }
 
@@ -283,6 +499,15 @@ initializeProgram initializes this program.
 func initializeProgram() {
 	var err error
 
+	// create chat session directory
+	if progConfig.ChatMode {
+		err = os.Mkdir(progConfig.ChatSessionDirectory, 0750)
+		if err != nil && !os.IsExist(err) {
+			fmt.Printf("error [%v] at os.Mkdir()\n", err)
+			os.Exit(1)
+		}
+	}
+
 	// create history directories
 	if progConfig.MarkdownHistory {
 		err = os.Mkdir(progConfig.MarkdownHistoryDirectory, 0750)
@@ -311,4 +536,11 @@ func initializeProgram() {
 		}
 		writeAssets(progConfig.HTMLHistoryDirectory)
 	}
+	if progConfig.JSONHistory {
+		err = os.Mkdir(progConfig.JSONHistoryDirectory, 0750)
+		if err != nil && !os.IsExist(err) {
+			fmt.Printf("error [%v] at os.Mkdir()\n", err)
+			os.Exit(1)
+		}
+	}
 }
@@ -0,0 +1,225 @@
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/generative-ai-go/genai"
+	"google.golang.org/api/iterator"
+)
+
+// cached content with attached files and system instruction (nil = no context cache in use)
+var contextCache *genai.CachedContent
+
+// ContextCacheContent represents data moved from requests into context cache
+type ContextCacheContent struct {
+	Parts       []genai.Part
+	Instruction *genai.Content
+	Tools       []*genai.Tool
+	ToolConfig  *genai.ToolConfig
+}
+
+// content of context cache in use
+var contextCacheContent ContextCacheContent
+
+/*
+attachmentParts builds parts of all attached files (uploaded and inlined).
+*/
+func attachmentParts() []genai.Part {
+	parts := []genai.Part{}
+	for _, uploadedFile := range uploadedFiles {
+		parts = append(parts, genai.FileData{URI: uploadedFile.URI})
+	}
+	for _, inlinedFile := range inlinedFiles {
+		parts = append(parts, inlinedFile.Parts...)
+	}
+	return parts
+}
+
+/*
+contextCacheName builds display name of context cache from its content (identifies reusable cache of previous program run).
+Tool declarations are part of the cache, so changed tools (e.g. function declarations) require a new cache.
+*/
+func contextCacheName(instruction *genai.Content, tools []*genai.Tool, toolConfig *genai.ToolConfig, parts []genai.Part) string {
+	hash := sha256.New()
+	hash.Write([]byte(progConfig.GeminiAiModel + "\n"))
+	if instruction != nil {
+		for _, part := range instruction.Parts {
+			hash.Write([]byte(fmt.Sprintf("%v\n", part)))
+		}
+	}
+	for _, data := range []any{tools, toolConfig} {
+		declaration, err := json.Marshal(data)
+		if err != nil {
+			fmt.Printf("error [%v] at json.Marshal()\n", err)
+		}
+		hash.Write(append(declaration, '\n'))
+	}
+	for _, part := range parts {
+		switch part := part.(type) {
+		case genai.FileData:
+			hash.Write([]byte(part.URI + "\n"))
+		case genai.Text:
+			hash.Write([]byte(string(part) + "\n"))
+		case genai.Blob:
+			hash.Write([]byte(part.MIMEType + "\n"))
+			hash.Write(part.Data)
+		}
+	}
+	return remoteFilePrefix + hex.EncodeToString(hash.Sum(nil))[:32]
+}
+
+/*
+findContextCache finds unexpired context cache with given display name (kept by previous program run).
+*/
+func findContextCache(ctx context.Context, client *genai.Client, name string) *genai.CachedContent {
+	iter := client.ListCachedContents(ctx)
+	for {
+		cachedContent, err := iter.Next()
+		if errors.Is(err, iterator.Done) {
+			return nil
+		}
+		if err != nil {
+			fmt.Printf("error [%v] listing context caches\n", err)
+			return nil
+		}
+		if cachedContent.DisplayName == name && time.Until(cachedContent.Expiration.ExpireTime) > time.Minute {
+			return cachedContent
+		}
+	}
+}
+
+/*
+createContextCache puts attached files and system instruction into context cache (used by all following prompts).
+If cache can't be created (e.g. too few tokens), attached files are sent with each prompt.
+*/
+func createContextCache(ctx context.Context, client *genai.Client, geminiModel *genai.GenerativeModel) {
+	parts := attachmentParts()
+	if len(parts) == 0 {
+		return
+	}
+	instruction := geminiModel.SystemInstruction
+	name := contextCacheName(instruction, geminiModel.Tools, geminiModel.ToolConfig, parts)
+
+	cachedContent := findContextCache(ctx, client, name)
+	if cachedContent != nil {
+		fmt.Printf("reusing context cache [%s] (expires %s)\n", cachedContent.Name, cachedContent.Expiration.ExpireTime.Local().Format("2006-01-02 15:04"))
+	} else {
+		_, err := withRetry(ctx, "creating context cache", func() error {
+			var err error
+			cachedContent, err = client.CreateCachedContent(ctx, &genai.CachedContent{
+				Model:             progConfig.GeminiAiModel,
+				DisplayName:       name,
+				SystemInstruction: instruction,
+				Contents:          []*genai.Content{genai.NewUserContent(parts...)},
+				Tools:             geminiModel.Tools,
+				ToolConfig:        geminiModel.ToolConfig,
+				Expiration:        genai.ExpireTimeOrTTL{TTL: time.Duration(progConfig.ContextCacheTTL) * time.Minute},
+			})
+			return err
+		})
+		if err != nil {
+			fmt.Printf("error [%v] creating context cache, attached files are sent with each prompt\n", err)
+			return
+		}
+		fmt.Printf("context cache [%s] created (expires %s)\n", cachedContent.Name, cachedContent.Expiration.ExpireTime.Local().Format("2006-01-02 15:04"))
+	}
+
+	// system instruction, tools and tool config are part of cached content (not allowed in requests)
+	contextCache = cachedContent
+	contextCacheContent = ContextCacheContent{Parts: parts, Instruction: instruction, Tools: geminiModel.Tools, ToolConfig: geminiModel.ToolConfig}
+	geminiModel.CachedContentName = cachedContent.Name
+	geminiModel.SystemInstruction = nil
+	geminiModel.Tools = nil
+	geminiModel.ToolConfig = nil
+}
+
+/*
+deleteContextCache deletes context cache and restores system instruction of AI model.
+*/
+func deleteContextCache(ctx context.Context, client *genai.Client, geminiModel *genai.GenerativeModel) {
+	if contextCache == nil {
+		return
+	}
+	err := client.DeleteCachedContent(ctx, contextCache.Name)
+	if err != nil {
+		fmt.Printf("error [%v] deleting context cache [%s]\n", err, contextCache.Name)
+	}
+	releaseContextCache(geminiModel)
+}
+
+/*
+releaseContextCache stops using context cache (data of cache is sent with requests again).
+*/
+func releaseContextCache(geminiModel *genai.GenerativeModel) {
+	geminiModel.CachedContentName = ""
+	geminiModel.SystemInstruction = contextCacheContent.Instruction
+	geminiModel.Tools = contextCacheContent.Tools
+	geminiModel.ToolConfig = contextCacheContent.ToolConfig
+	contextCache = nil
+	contextCacheContent = ContextCacheContent{}
+}
+
+/*
+updateContextCache replaces context cache after changes of attachment set (e.g. '/attach', '/sync').
+*/
+func updateContextCache(ctx context.Context, client *genai.Client, geminiModel *genai.GenerativeModel) {
+	if !progConfig.ContextCache {
+		return
+	}
+	deleteContextCache(ctx, client, geminiModel)
+	createContextCache(ctx, client, geminiModel)
+}
+
+/*
+extendContextCache extends expiration of context cache (if less than half of TTL is left).
+Expired context caches are recreated.
+*/
+func extendContextCache(ctx context.Context, client *genai.Client, geminiModel *genai.GenerativeModel) {
+	if contextCache == nil {
+		return
+	}
+	ttl := time.Duration(progConfig.ContextCacheTTL) * time.Minute
+	if time.Until(contextCache.Expiration.ExpireTime) > ttl/2 {
+		return
+	}
+
+	cachedContent, err := client.UpdateCachedContent(ctx, contextCache, &genai.CachedContentToUpdate{Expiration: &genai.ExpireTimeOrTTL{TTL: ttl}})
+	if err != nil {
+		fmt.Printf("error [%v] extending context cache, recreating cache\n", err)
+		releaseContextCache(geminiModel)
+		createContextCache(ctx, client, geminiModel)
+		return
+	}
+	contextCache = cachedContent
+}
+
+/*
+closeContextCache extends (keep) or deletes context cache at program termination.
+*/
+func closeContextCache(ctx context.Context, client *genai.Client) {
+	if contextCache == nil {
+		return
+	}
+	if !progConfig.ContextCacheKeep {
+		fmt.Printf("deleting context cache [%s]\n", contextCache.Name)
+		err := client.DeleteCachedContent(ctx, contextCache.Name)
+		if err != nil {
+			fmt.Printf("error [%v] deleting context cache\n", err)
+		}
+		return
+	}
+
+	ttl := time.Duration(progConfig.ContextCacheTTL) * time.Minute
+	cachedContent, err := client.UpdateCachedContent(ctx, contextCache, &genai.CachedContentToUpdate{Expiration: &genai.ExpireTimeOrTTL{TTL: ttl}})
+	if err != nil {
+		fmt.Printf("error [%v] extending context cache\n", err)
+		return
+	}
+	fmt.Printf("keeping context cache [%s] (expires %s)\n", cachedContent.Name, cachedContent.Expiration.ExpireTime.Local().Format("2006-01-02 15:04"))
+}
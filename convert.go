@@ -0,0 +1,907 @@
+package main
+
+import (
+	"archive/zip"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// DocumentConverter represents a local converter of a document format into markdown (pure Go)
+type DocumentConverter struct {
+	Name    string
+	Convert func(filename string) (string, error)
+}
+
+// converters per file extension (formats not supported by Gemini)
+var documentConverters = map[string]DocumentConverter{
+	".docx":  {Name: "docx", Convert: convertDocx},
+	".odt":   {Name: "odt", Convert: convertOdt},
+	".xlsx":  {Name: "xlsx", Convert: convertXlsx},
+	".pptx":  {Name: "pptx", Convert: convertPptx},
+	".ipynb": {Name: "ipynb", Convert: convertNotebook},
+	".epub":  {Name: "epub", Convert: convertEpub},
+}
+
+// source documents of converted files (converted filename -> source filename)
+var (
+	convertedSources   = map[string]string{}
+	convertedSourcesMu sync.Mutex
+)
+
+/*
+findDocumentConverter finds converter for local file (false = no conversion).
+*/
+func findDocumentConverter(filename string) (DocumentConverter, bool) {
+	if !progConfig.UploadConvertDocuments {
+		return DocumentConverter{}, false
+	}
+	converter, ok := documentConverters[strings.ToLower(filepath.Ext(filename))]
+	return converter, ok
+}
+
+/*
+convertedFilename builds filename of converted document (e.g. 'docs/report.docx' -> 'converted/docs/report.docx.md').
+*/
+func convertedFilename(filename string) string {
+	if !filepath.IsLocal(filename) {
+		absFilename, err := filepath.Abs(filename)
+		if err == nil {
+			filename = absFilename
+		}
+		filename = strings.TrimPrefix(filename, filepath.VolumeName(filename))
+		filename = strings.ReplaceAll(filename, "..", "__")
+	}
+	return filepath.Join(progConfig.UploadConvertDirectory, filename+".md")
+}
+
+/*
+documentSource gets source document of converted file (other files are their own source).
+*/
+func documentSource(filename string) string {
+	convertedSourcesMu.Lock()
+	defer convertedSourcesMu.Unlock()
+
+	if source, ok := convertedSources[filename]; ok {
+		return source
+	}
+	return filename
+}
+
+/*
+convertDocument converts local document into markdown (with header naming the source document).
+*/
+func convertDocument(filename string, converter DocumentConverter) (string, error) {
+	markdown, err := converter.Convert(filename)
+	if err != nil {
+		return "", err
+	}
+	header := fmt.Sprintf("<!-- converted from [%s] (%s converter) -->\n\n", filepath.ToSlash(filename), converter.Name)
+	return header + strings.TrimSpace(markdown) + "\n", nil
+}
+
+/*
+convertDocuments converts documents (e.g. .docx, .xlsx) into markdown files and returns filenames to upload.
+Converted files are written to UploadConvertDirectory, files which couldn't be converted are kept as they are.
+*/
+func convertDocuments(filenames []string) []string {
+	result := []string{}
+	converted := []string{}
+	for _, filename := range filenames {
+		converter, ok := findDocumentConverter(filename)
+		if !ok {
+			result = append(result, filename)
+			continue
+		}
+		markdown, err := convertDocument(filename, converter)
+		if err != nil {
+			fmt.Printf("error [%v] converting file [%s], using original\n", err, filename)
+			result = append(result, filename)
+			continue
+		}
+		target := convertedFilename(filename)
+		err = os.MkdirAll(filepath.Dir(target), 0750)
+		if err == nil {
+			err = os.WriteFile(target, []byte(markdown), 0640)
+		}
+		if err != nil {
+			fmt.Printf("error [%v] writing converted file [%s], using original\n", err, target)
+			result = append(result, filename)
+			continue
+		}
+		result = append(result, target)
+		convertedSourcesMu.Lock()
+		convertedSources[target] = filename
+		convertedSourcesMu.Unlock()
+		converted = append(converted, fmt.Sprintf("  %s ... %s (%s converter, %.1f KiB)", filename, target, converter.Name, float64(len(markdown))/1024.0))
+	}
+
+	if len(converted) > 0 {
+		fmt.Printf("\nConverted files:\n%s\n", strings.Join(converted, "\n"))
+	}
+	return result
+}
+
+/*
+readZipFile reads file from zip archive (office documents, epub).
+*/
+func readZipFile(archive *zip.ReadCloser, name string) ([]byte, error) {
+	for _, file := range archive.File {
+		if file.Name != name {
+			continue
+		}
+		reader, err := file.Open()
+		if err != nil {
+			return nil, err
+		}
+		defer reader.Close()
+		return io.ReadAll(reader)
+	}
+	return nil, fmt.Errorf("file [%s] not found in archive", name)
+}
+
+/*
+xmlAttribute gets value of attribute (given by local name) of xml element.
+*/
+func xmlAttribute(element xml.StartElement, name string) string {
+	for _, attribute := range element.Attr {
+		if attribute.Name.Local == name {
+			return attribute.Value
+		}
+	}
+	return ""
+}
+
+/*
+markdownTable formats rows as markdown table (first row = header, short rows are padded).
+*/
+func markdownTable(rows [][]string) string {
+	columns := 0
+	for _, row := range rows {
+		columns = max(columns, len(row))
+	}
+	if len(rows) == 0 || columns == 0 {
+		return ""
+	}
+
+	var table strings.Builder
+	for i, row := range rows {
+		cells := make([]string, columns)
+		for j := range cells {
+			if j < len(row) {
+				cells[j] = strings.ReplaceAll(strings.ReplaceAll(strings.TrimSpace(row[j]), "|", "\\|"), "\n", "<br>")
+			}
+		}
+		table.WriteString("| " + strings.Join(cells, " | ") + " |\n")
+		if i == 0 {
+			table.WriteString(strings.Repeat("| --- ", columns) + "|\n")
+		}
+	}
+	return table.String()
+}
+
+/*
+convertDocx converts Word document (.docx) into markdown (headings, paragraphs, lists, tables).
+*/
+func convertDocx(filename string) (string, error) {
+	archive, err := zip.OpenReader(filename)
+	if err != nil {
+		return "", err
+	}
+	defer archive.Close()
+
+	data, err := readZipFile(archive, "word/document.xml")
+	if err != nil {
+		return "", err
+	}
+
+	var markdown strings.Builder
+	var paragraph strings.Builder
+	prefix := ""
+	inList := false
+	var rows [][]string
+	var row []string
+	var cell strings.Builder
+	tableDepth := 0
+
+	decoder := xml.NewDecoder(strings.NewReader(string(data)))
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return "", err
+		}
+		switch element := token.(type) {
+		case xml.StartElement:
+			switch element.Name.Local {
+			case "p":
+				paragraph.Reset()
+				prefix = ""
+			case "pStyle":
+				style := strings.ToLower(xmlAttribute(element, "val"))
+				if style == "title" {
+					prefix = "# "
+				} else if level, err := strconv.Atoi(strings.TrimPrefix(style, "heading")); err == nil && strings.HasPrefix(style, "heading") {
+					prefix = strings.Repeat("#", min(max(level, 1), 6)) + " "
+				}
+			case "numPr":
+				if prefix == "" {
+					prefix = "- "
+				}
+			case "tab":
+				paragraph.WriteString("\t")
+			case "br", "cr":
+				paragraph.WriteString("\n")
+			case "tbl":
+				tableDepth++
+				if tableDepth == 1 {
+					rows = [][]string{}
+				}
+			case "tr":
+				if tableDepth == 1 {
+					row = []string{}
+				}
+			case "tc":
+				if tableDepth == 1 {
+					cell.Reset()
+				}
+			case "t":
+				var text string
+				err = decoder.DecodeElement(&text, &element)
+				if err != nil {
+					return "", err
+				}
+				paragraph.WriteString(text)
+			}
+		case xml.EndElement:
+			switch element.Name.Local {
+			case "p":
+				text := strings.TrimSpace(paragraph.String())
+				switch {
+				case tableDepth > 0:
+					if cell.Len() > 0 && text != "" {
+						cell.WriteString("\n")
+					}
+					cell.WriteString(text)
+				case text != "":
+					// list items are separated by newline, list is terminated by empty line
+					listItem := prefix == "- "
+					if inList && !listItem {
+						markdown.WriteString("\n")
+					}
+					markdown.WriteString(prefix + text + "\n")
+					if !listItem {
+						markdown.WriteString("\n")
+					}
+					inList = listItem
+				}
+			case "tc":
+				if tableDepth == 1 {
+					row = append(row, cell.String())
+				}
+			case "tr":
+				if tableDepth == 1 {
+					rows = append(rows, row)
+				}
+			case "tbl":
+				tableDepth--
+				if tableDepth == 0 {
+					if inList {
+						markdown.WriteString("\n")
+						inList = false
+					}
+					markdown.WriteString(markdownTable(rows) + "\n")
+				}
+			}
+		}
+	}
+	return markdown.String(), nil
+}
+
+/*
+convertOdt converts OpenDocument text (.odt) into markdown (headings, paragraphs, lists, tables).
+*/
+func convertOdt(filename string) (string, error) {
+	archive, err := zip.OpenReader(filename)
+	if err != nil {
+		return "", err
+	}
+	defer archive.Close()
+
+	data, err := readZipFile(archive, "content.xml")
+	if err != nil {
+		return "", err
+	}
+
+	var markdown strings.Builder
+	var paragraph strings.Builder
+	paragraphDepth := 0 // paragraphs can be nested (e.g. in notes)
+	prefix := ""
+	listDepth := 0
+	var rows [][]string
+	var row []string
+	var cell strings.Builder
+	tableDepth := 0
+
+	decoder := xml.NewDecoder(strings.NewReader(string(data)))
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return "", err
+		}
+		switch element := token.(type) {
+		case xml.StartElement:
+			switch element.Name.Local {
+			case "h":
+				paragraphDepth++
+				paragraph.Reset()
+				level, err := strconv.Atoi(xmlAttribute(element, "outline-level"))
+				if err != nil {
+					level = 1
+				}
+				prefix = strings.Repeat("#", min(max(level, 1), 6)) + " "
+			case "p":
+				paragraphDepth++
+				if paragraphDepth == 1 {
+					paragraph.Reset()
+					prefix = ""
+					if listDepth > 0 {
+						prefix = strings.Repeat("  ", listDepth-1) + "- "
+					}
+				}
+			case "list":
+				listDepth++
+			case "s":
+				count, err := strconv.Atoi(xmlAttribute(element, "c"))
+				if err != nil {
+					count = 1
+				}
+				paragraph.WriteString(strings.Repeat(" ", count))
+			case "tab":
+				paragraph.WriteString("\t")
+			case "line-break":
+				paragraph.WriteString("\n")
+			case "note":
+				// footnotes are skipped (would be merged into paragraph text)
+				err = decoder.Skip()
+				if err != nil {
+					return "", err
+				}
+			case "table":
+				tableDepth++
+				if tableDepth == 1 {
+					rows = [][]string{}
+				}
+			case "table-row":
+				if tableDepth == 1 {
+					row = []string{}
+				}
+			case "table-cell":
+				if tableDepth == 1 {
+					cell.Reset()
+				}
+			}
+		case xml.CharData:
+			if paragraphDepth > 0 {
+				paragraph.Write(element)
+			}
+		case xml.EndElement:
+			switch element.Name.Local {
+			case "h", "p":
+				paragraphDepth--
+				if paragraphDepth > 0 {
+					continue
+				}
+				text := strings.TrimSpace(paragraph.String())
+				switch {
+				case tableDepth > 0:
+					if cell.Len() > 0 && text != "" {
+						cell.WriteString("\n")
+					}
+					cell.WriteString(text)
+				case text != "":
+					markdown.WriteString(prefix + text + "\n")
+					if listDepth == 0 {
+						markdown.WriteString("\n")
+					}
+				}
+			case "list":
+				listDepth--
+				if listDepth == 0 {
+					markdown.WriteString("\n")
+				}
+			case "table-cell":
+				if tableDepth == 1 {
+					row = append(row, cell.String())
+				}
+			case "table-row":
+				if tableDepth == 1 {
+					rows = append(rows, row)
+				}
+			case "table":
+				tableDepth--
+				if tableDepth == 0 {
+					markdown.WriteString(markdownTable(rows) + "\n")
+				}
+			}
+		}
+	}
+	return markdown.String(), nil
+}
+
+// cell reference in spreadsheet (e.g. 'B12')
+var cellReferencePattern = regexp.MustCompile(`^([A-Z]+)[0-9]+$`)
+
+/*
+convertXlsx converts Excel workbook (.xlsx) into markdown (one table per sheet, first row = header).
+*/
+func convertXlsx(filename string) (string, error) {
+	archive, err := zip.OpenReader(filename)
+	if err != nil {
+		return "", err
+	}
+	defer archive.Close()
+
+	// shared strings (optional)
+	sharedStrings := []string{}
+	if data, err := readZipFile(archive, "xl/sharedStrings.xml"); err == nil {
+		var sst struct {
+			Items []struct {
+				Text string `xml:"t"`
+				Runs []struct {
+					Text string `xml:"t"`
+				} `xml:"r"`
+			} `xml:"si"`
+		}
+		err = xml.Unmarshal(data, &sst)
+		if err != nil {
+			return "", err
+		}
+		for _, item := range sst.Items {
+			text := item.Text
+			for _, run := range item.Runs {
+				text += run.Text
+			}
+			sharedStrings = append(sharedStrings, text)
+		}
+	}
+
+	// sheets (names and order from workbook, files from relationships)
+	data, err := readZipFile(archive, "xl/workbook.xml")
+	if err != nil {
+		return "", err
+	}
+	var workbook struct {
+		Sheets []struct {
+			Name string `xml:"name,attr"`
+			ID   string `xml:"http://schemas.openxmlformats.org/officeDocument/2006/relationships id,attr"`
+		} `xml:"sheets>sheet"`
+	}
+	err = xml.Unmarshal(data, &workbook)
+	if err != nil {
+		return "", err
+	}
+	targets, err := readRelationships(archive, "xl/_rels/workbook.xml.rels", "xl")
+	if err != nil {
+		return "", err
+	}
+
+	var markdown strings.Builder
+	for _, sheet := range workbook.Sheets {
+		data, err := readZipFile(archive, targets[sheet.ID])
+		if err != nil {
+			return "", err
+		}
+		var worksheet struct {
+			Rows []struct {
+				Cells []struct {
+					Reference  string `xml:"r,attr"`
+					Type       string `xml:"t,attr"`
+					Value      string `xml:"v"`
+					InlineText string `xml:"is>t"`
+				} `xml:"c"`
+			} `xml:"sheetData>row"`
+		}
+		err = xml.Unmarshal(data, &worksheet)
+		if err != nil {
+			return "", err
+		}
+
+		rows := [][]string{}
+		for _, sheetRow := range worksheet.Rows {
+			row := []string{}
+			for _, sheetCell := range sheetRow.Cells {
+				value := sheetCell.Value
+				switch sheetCell.Type {
+				case "s":
+					index, err := strconv.Atoi(value)
+					if err == nil && index >= 0 && index < len(sharedStrings) {
+						value = sharedStrings[index]
+					}
+				case "inlineStr":
+					value = sheetCell.InlineText
+				case "b":
+					value = map[string]string{"0": "FALSE", "1": "TRUE"}[value]
+				}
+				// empty cells are omitted in sheet data (position given by cell reference)
+				column := len(row)
+				if match := cellReferencePattern.FindStringSubmatch(sheetCell.Reference); match != nil {
+					column = columnIndex(match[1])
+				}
+				for len(row) < column {
+					row = append(row, "")
+				}
+				row = append(row, value)
+			}
+			rows = append(rows, row)
+		}
+
+		markdown.WriteString(fmt.Sprintf("## Sheet: %s\n\n", sheet.Name))
+		if len(rows) == 0 {
+			markdown.WriteString("(empty)\n\n")
+			continue
+		}
+		markdown.WriteString(markdownTable(rows) + "\n")
+	}
+	return markdown.String(), nil
+}
+
+/*
+columnIndex converts spreadsheet column name into zero-based index (e.g. 'A' -> 0, 'AB' -> 27).
+*/
+func columnIndex(column string) int {
+	index := 0
+	for _, r := range column {
+		index = index*26 + int(r-'A') + 1
+	}
+	return index - 1
+}
+
+/*
+readRelationships reads relationships of office document part (id -> archive path of target).
+*/
+func readRelationships(archive *zip.ReadCloser, name, base string) (map[string]string, error) {
+	data, err := readZipFile(archive, name)
+	if err != nil {
+		return nil, err
+	}
+	var relationships struct {
+		Items []struct {
+			ID     string `xml:"Id,attr"`
+			Target string `xml:"Target,attr"`
+		} `xml:"Relationship"`
+	}
+	err = xml.Unmarshal(data, &relationships)
+	if err != nil {
+		return nil, err
+	}
+
+	targets := map[string]string{}
+	for _, item := range relationships.Items {
+		if strings.HasPrefix(item.Target, "/") {
+			targets[item.ID] = strings.TrimPrefix(item.Target, "/")
+		} else {
+			targets[item.ID] = path.Join(base, item.Target)
+		}
+	}
+	return targets, nil
+}
+
+/*
+convertPptx converts PowerPoint presentation (.pptx) into markdown (text of each slide).
+*/
+func convertPptx(filename string) (string, error) {
+	archive, err := zip.OpenReader(filename)
+	if err != nil {
+		return "", err
+	}
+	defer archive.Close()
+
+	data, err := readZipFile(archive, "ppt/presentation.xml")
+	if err != nil {
+		return "", err
+	}
+	var presentation struct {
+		Slides []struct {
+			ID string `xml:"http://schemas.openxmlformats.org/officeDocument/2006/relationships id,attr"`
+		} `xml:"sldIdLst>sldId"`
+	}
+	err = xml.Unmarshal(data, &presentation)
+	if err != nil {
+		return "", err
+	}
+	targets, err := readRelationships(archive, "ppt/_rels/presentation.xml.rels", "ppt")
+	if err != nil {
+		return "", err
+	}
+
+	var markdown strings.Builder
+	for i, slide := range presentation.Slides {
+		data, err := readZipFile(archive, targets[slide.ID])
+		if err != nil {
+			return "", err
+		}
+		markdown.WriteString(fmt.Sprintf("## Slide %d\n\n", i+1))
+
+		// paragraphs (a:p) with text runs (a:t)
+		var paragraph strings.Builder
+		decoder := xml.NewDecoder(strings.NewReader(string(data)))
+		for {
+			token, err := decoder.Token()
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				return "", err
+			}
+			switch element := token.(type) {
+			case xml.StartElement:
+				switch element.Name.Local {
+				case "p":
+					paragraph.Reset()
+				case "br":
+					paragraph.WriteString("\n")
+				case "t":
+					var text string
+					err = decoder.DecodeElement(&text, &element)
+					if err != nil {
+						return "", err
+					}
+					paragraph.WriteString(text)
+				}
+			case xml.EndElement:
+				if element.Name.Local == "p" {
+					if text := strings.TrimSpace(paragraph.String()); text != "" {
+						markdown.WriteString(text + "\n\n")
+					}
+				}
+			}
+		}
+	}
+	return markdown.String(), nil
+}
+
+// NotebookSource represents cell source or output text of Jupyter notebook (string or list of lines)
+type NotebookSource string
+
+/*
+UnmarshalJSON unmarshals notebook text given as string or list of lines.
+*/
+func (ns *NotebookSource) UnmarshalJSON(data []byte) error {
+	var lines []string
+	if err := json.Unmarshal(data, &lines); err == nil {
+		*ns = NotebookSource(strings.Join(lines, ""))
+		return nil
+	}
+	var text string
+	if err := json.Unmarshal(data, &text); err != nil {
+		return err
+	}
+	*ns = NotebookSource(text)
+	return nil
+}
+
+/*
+convertNotebook converts Jupyter notebook (.ipynb) into markdown (markdown cells, code cells and their outputs).
+*/
+func convertNotebook(filename string) (string, error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return "", err
+	}
+	var notebook struct {
+		Metadata struct {
+			Kernelspec struct {
+				Language string `json:"language"`
+			} `json:"kernelspec"`
+			LanguageInfo struct {
+				Name string `json:"name"`
+			} `json:"language_info"`
+		} `json:"metadata"`
+		Cells []struct {
+			CellType string         `json:"cell_type"`
+			Source   NotebookSource `json:"source"`
+			Outputs  []struct {
+				OutputType string                    `json:"output_type"`
+				Text       NotebookSource            `json:"text"`
+				Data       map[string]NotebookSource `json:"data"`
+				EName      string                    `json:"ename"`
+				EValue     string                    `json:"evalue"`
+			} `json:"outputs"`
+		} `json:"cells"`
+	}
+	err = json.Unmarshal(data, &notebook)
+	if err != nil {
+		return "", err
+	}
+	language := notebook.Metadata.LanguageInfo.Name
+	if language == "" {
+		language = notebook.Metadata.Kernelspec.Language
+	}
+
+	var markdown strings.Builder
+	for i, cell := range notebook.Cells {
+		source := strings.TrimRight(string(cell.Source), "\n")
+		switch cell.CellType {
+		case "markdown":
+			markdown.WriteString(source + "\n\n")
+		case "code":
+			markdown.WriteString(fmt.Sprintf("**Cell %d (code):**\n\n```%s\n%s\n```\n\n", i+1, language, source))
+			for _, output := range cell.Outputs {
+				text := ""
+				switch output.OutputType {
+				case "stream":
+					text = string(output.Text)
+				case "execute_result", "display_data":
+					if plain, ok := output.Data["text/plain"]; ok {
+						text = string(plain)
+					}
+					mimeTypes := []string{}
+					for mimeType := range output.Data {
+						if mimeType != "text/plain" {
+							mimeTypes = append(mimeTypes, mimeType)
+						}
+					}
+					sort.Strings(mimeTypes)
+					if len(mimeTypes) > 0 && text == "" {
+						text = fmt.Sprintf("[%s output]", strings.Join(mimeTypes, ", "))
+					}
+				case "error":
+					text = fmt.Sprintf("%s: %s", output.EName, output.EValue)
+				}
+				if text = strings.TrimRight(text, "\n"); text != "" {
+					markdown.WriteString(fmt.Sprintf("**Output (%s):**\n\n```text\n%s\n```\n\n", output.OutputType, text))
+				}
+			}
+		default:
+			markdown.WriteString(fmt.Sprintf("```\n%s\n```\n\n", source))
+		}
+	}
+	return markdown.String(), nil
+}
+
+/*
+convertEpub converts e-book (.epub) into markdown (chapters in reading order).
+*/
+func convertEpub(filename string) (string, error) {
+	archive, err := zip.OpenReader(filename)
+	if err != nil {
+		return "", err
+	}
+	defer archive.Close()
+
+	// container -> package document (manifest and spine)
+	data, err := readZipFile(archive, "META-INF/container.xml")
+	if err != nil {
+		return "", err
+	}
+	var container struct {
+		Rootfiles []struct {
+			FullPath string `xml:"full-path,attr"`
+		} `xml:"rootfiles>rootfile"`
+	}
+	err = xml.Unmarshal(data, &container)
+	if err != nil {
+		return "", err
+	}
+	if len(container.Rootfiles) == 0 {
+		return "", fmt.Errorf("no package document found")
+	}
+	packagePath := container.Rootfiles[0].FullPath
+	data, err = readZipFile(archive, packagePath)
+	if err != nil {
+		return "", err
+	}
+	var opf struct {
+		Title string `xml:"metadata>title"`
+		Items []struct {
+			ID   string `xml:"id,attr"`
+			Href string `xml:"href,attr"`
+		} `xml:"manifest>item"`
+		Spine []struct {
+			IDRef string `xml:"idref,attr"`
+		} `xml:"spine>itemref"`
+	}
+	err = xml.Unmarshal(data, &opf)
+	if err != nil {
+		return "", err
+	}
+	hrefs := map[string]string{}
+	for _, item := range opf.Items {
+		hrefs[item.ID] = item.Href
+	}
+
+	var markdown strings.Builder
+	if opf.Title != "" {
+		markdown.WriteString("# " + opf.Title + "\n\n")
+	}
+	for _, itemref := range opf.Spine {
+		href, ok := hrefs[itemref.IDRef]
+		if !ok {
+			continue
+		}
+		data, err := readZipFile(archive, path.Join(path.Dir(packagePath), href))
+		if err != nil {
+			return "", err
+		}
+		text, err := convertXhtml(data)
+		if err != nil {
+			return "", fmt.Errorf("%s: %w", href, err)
+		}
+		markdown.WriteString(text)
+	}
+	return markdown.String(), nil
+}
+
+/*
+convertXhtml converts XHTML document (e.g. epub chapter) into markdown (headings, paragraphs, lists).
+*/
+func convertXhtml(data []byte) (string, error) {
+	var markdown strings.Builder
+	var block strings.Builder
+	prefix := ""
+	flush := func() {
+		if text := strings.Join(strings.Fields(block.String()), " "); text != "" {
+			markdown.WriteString(prefix + text + "\n\n")
+		}
+		block.Reset()
+		prefix = ""
+	}
+
+	decoder := xml.NewDecoder(strings.NewReader(string(data)))
+	decoder.Strict = false
+	decoder.AutoClose = xml.HTMLAutoClose
+	decoder.Entity = xml.HTMLEntity
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return "", err
+		}
+		switch element := token.(type) {
+		case xml.StartElement:
+			switch name := strings.ToLower(element.Name.Local); name {
+			case "head", "script", "style":
+				err = decoder.Skip()
+				if err != nil {
+					return "", err
+				}
+			case "h1", "h2", "h3", "h4", "h5", "h6":
+				flush()
+				prefix = strings.Repeat("#", int(name[1]-'0')) + " "
+			case "li":
+				flush()
+				prefix = "- "
+			case "p", "div", "blockquote", "tr":
+				flush()
+			case "br":
+				block.WriteString("\n")
+			}
+		case xml.CharData:
+			block.Write(element)
+		case xml.EndElement:
+			switch strings.ToLower(element.Name.Local) {
+			case "h1", "h2", "h3", "h4", "h5", "h6", "li", "p", "div", "blockquote", "tr":
+				flush()
+			}
+		}
+	}
+	flush()
+	return markdown.String(), nil
+}
@@ -0,0 +1,204 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/google/generative-ai-go/genai"
+)
+
+// PromptDirective represents a directive line inside prompt text (e.g. '@file: docs/spec.pdf')
+type PromptDirective struct {
+	Kind     string // file, glob, cmd, schema
+	Argument string
+}
+
+// PromptAttachment represents data referenced by a single prompt (resolved directive)
+type PromptAttachment struct {
+	Description string       // shown in 'Data referenced by this Prompt only'
+	Parts       []genai.Part // file data (uploaded) or text/blob (inlined)
+	File        *genai.File  // uploaded remote file (nil = inlined data)
+}
+
+// directive line: '@file: path', '@glob: pattern', '@cmd: command line' or '@schema: path' (JSON response)
+var promptDirectivePattern = regexp.MustCompile(`^@(file|glob|cmd|schema):\s*(.+)$`)
+
+// remote files uploaded for single prompts in chat mode (still referenced by chat history)
+var promptUploadedFiles []*genai.File
+
+/*
+parsePromptDirectives extracts directive lines from prompt text and returns stripped prompt text.
+*/
+func parsePromptDirectives(prompt string) (string, []PromptDirective) {
+	directives := []PromptDirective{}
+	lines := []string{}
+	for _, line := range strings.Split(prompt, "\n") {
+		match := promptDirectivePattern.FindStringSubmatch(strings.TrimSpace(line))
+		if match == nil {
+			lines = append(lines, line)
+			continue
+		}
+		directives = append(directives, PromptDirective{Kind: match[1], Argument: strings.TrimSpace(match[2])})
+	}
+	return strings.TrimSpace(strings.Join(lines, "\n")), directives
+}
+
+/*
+checkRemotePromptDirectives checks directives of prompt received via localhost (any local process or web page
+can send such prompts): commands and files outside of working directory are refused.
+*/
+func checkRemotePromptDirectives(prompt string) error {
+	if !progConfig.PromptDirectives {
+		return nil
+	}
+	_, directives := parsePromptDirectives(prompt)
+	for _, directive := range directives {
+		if directive.Kind == "cmd" {
+			return fmt.Errorf("directive '@cmd:' not allowed in prompts received via localhost")
+		}
+		if !isInsideWorkingDirectory(directive.Argument) {
+			return fmt.Errorf("directive '@%s: %s' outside of working directory not allowed in prompts received via localhost",
+				directive.Kind, directive.Argument)
+		}
+	}
+	return nil
+}
+
+/*
+isInsideWorkingDirectory checks if path (file, directory or glob pattern) is inside of working directory (symlinks resolved).
+*/
+func isInsideWorkingDirectory(path string) bool {
+	workingDirectory, err := os.Getwd()
+	if err != nil {
+		return false
+	}
+	absolutePath, err := filepath.Abs(path)
+	if err != nil {
+		return false
+	}
+	if resolvedPath, err := filepath.EvalSymlinks(absolutePath); err == nil {
+		absolutePath = resolvedPath
+	}
+	if resolvedDirectory, err := filepath.EvalSymlinks(workingDirectory); err == nil {
+		workingDirectory = resolvedDirectory
+	}
+	relativePath, err := filepath.Rel(workingDirectory, absolutePath)
+	return err == nil && filepath.IsLocal(relativePath)
+}
+
+/*
+resolvePromptDirectives uploads files and globs and runs commands of directives (data for current prompt only).
+*/
+func resolvePromptDirectives(ctx context.Context, client *genai.Client, directives []PromptDirective) []PromptAttachment {
+	attachments := []PromptAttachment{}
+
+	// files and globs
+	entries := []string{}
+	for _, directive := range directives {
+		if directive.Kind == "file" || directive.Kind == "glob" {
+			entries = append(entries, directive.Argument)
+		}
+	}
+	if len(entries) > 0 {
+		filenames, skippedFiles := resolveUploadFiles(entries)
+		if len(skippedFiles) > 0 {
+			fmt.Printf("%d %s of prompt directives skipped (gitignore, filters)\n", len(skippedFiles), pluralize(len(skippedFiles), "file"))
+		}
+		files, inlineFiles, err := prepareFiles(ctx, client, filenames)
+		if err != nil {
+			fmt.Printf("error [%v] uploading files of prompt directives\n", err)
+		}
+		for _, file := range files {
+			attachments = append(attachments, PromptAttachment{
+				Description: fmt.Sprintf("%s (%.1f KiB, %s)", file.DisplayName, float64(file.SizeBytes)/1024.0, file.MIMEType),
+				Parts:       []genai.Part{genai.FileData{URI: file.URI}},
+				File:        file,
+			})
+		}
+		for _, inlineFile := range inlineFiles {
+			attachments = append(attachments, PromptAttachment{
+				Description: fmt.Sprintf("%s (inline, %.1f KiB, %s)", inlineFile.Filename, float64(inlineFile.Size)/1024.0, inlineFile.MIMEType),
+				Parts:       inlineFile.Parts,
+			})
+		}
+	}
+
+	// commands (output is inlined as text)
+	for _, directive := range directives {
+		if directive.Kind != "cmd" {
+			continue
+		}
+		if !progConfig.PromptDirectiveCommands {
+			fmt.Printf("error: command directives not enabled, [%s] ignored\n", directive.Argument)
+			continue
+		}
+		output, err := runDirectiveCommand(ctx, directive.Argument)
+		status := "exit status 0"
+		if err != nil {
+			status = err.Error()
+		}
+		text := fmt.Sprintf("Output of command `%s` (%s):\n\n```\n%s\n```\n", directive.Argument, status, strings.TrimRight(output, "\n"))
+		attachments = append(attachments, PromptAttachment{
+			Description: fmt.Sprintf("$ %s (%s, %.1f KiB output)", directive.Argument, status, float64(len(output))/1024.0),
+			Parts:       []genai.Part{genai.Text(text)},
+		})
+	}
+
+	return attachments
+}
+
+/*
+runDirectiveCommand runs command of directive (no shell) and returns combined output (stdout and stderr).
+*/
+func runDirectiveCommand(ctx context.Context, commandLine string) (string, error) {
+	args := splitCommandLine(commandLine)
+	if len(args) == 0 {
+		return "", fmt.Errorf("empty command")
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, time.Duration(progConfig.PromptDirectiveCommandTimeout)*time.Second)
+	defer cancel()
+
+	fmt.Printf("running command [%s] ...\n", commandLine)
+	output, err := exec.CommandContext(ctx, args[0], args[1:]...).CombinedOutput()
+	return string(output), err
+}
+
+/*
+cleanupPromptAttachments deletes remote files uploaded for single prompt (in chat mode kept until program termination).
+*/
+func cleanupPromptAttachments(ctx context.Context, client *genai.Client, attachments []PromptAttachment) {
+	deleted := map[string]bool{}
+	for _, attachment := range attachments {
+		if attachment.File == nil {
+			continue
+		}
+		if chatSession != nil || progConfig.UploadKeepFiles {
+			uploadedFilesMu.Lock()
+			promptUploadedFiles = append(promptUploadedFiles, attachment.File)
+			uploadedFilesMu.Unlock()
+			continue
+		}
+		// remote file shared with attached file (same content) or deleted already
+		if _, inUse := remoteFileAttachment(attachment.File); inUse || deleted[attachment.File.Name] {
+			continue
+		}
+		deleted[attachment.File.Name] = true
+		err := client.DeleteFile(ctx, attachment.File.Name)
+		if err != nil {
+			fmt.Printf("error [%v] deleting remote file [%s] of prompt directive\n", err, attachment.File.DisplayName)
+		}
+		if uploadCache != nil {
+			uploadCache.remove(attachment.File.Name)
+		}
+	}
+	if uploadCache != nil {
+		uploadCache.save()
+	}
+}
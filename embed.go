@@ -40,6 +40,9 @@ var assetsGeminiPromptEbebebSvg []byte
 //go:embed assets/copy-to-clipboard.js
 var assetsCopyToClipboardJs []byte
 
+//go:embed assets/live-view.js
+var assetsLiveViewJs []byte
+
 func writeAssets(basepath string) {
 	filename := basepath + "/assets/gemini-prompt.css"
 	err := os.WriteFile(filename, assetsGeminiPromptCSS, 0666)
@@ -64,4 +67,10 @@ func writeAssets(basepath string) {
 	if err != nil {
 		log.Fatalf("embed: error [%v] at os.WriteFile(), file = [%s]", err, filename)
 	}
+
+	filename = basepath + "/assets/live-view.js"
+	err = os.WriteFile(filename, assetsLiveViewJs, 0666)
+	if err != nil {
+		log.Fatalf("embed: error [%v] at os.WriteFile(), file = [%s]", err, filename)
+	}
 }
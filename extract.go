@@ -0,0 +1,303 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/yuin/goldmark/ast"
+	"github.com/yuin/goldmark/text"
+)
+
+// CodeBlock represents fenced code block of response
+type CodeBlock struct {
+	Language string
+	Hint     string // filename hint from info string or preceding heading (empty = none)
+	Source   string // origin of filename (info, heading, language)
+	Code     string
+}
+
+// filename extensions of common code block languages
+var codeBlockExtensions = map[string]string{
+	"bash":       ".sh",
+	"c":          ".c",
+	"cpp":        ".cpp",
+	"csharp":     ".cs",
+	"css":        ".css",
+	"dockerfile": ".dockerfile",
+	"go":         ".go",
+	"html":       ".html",
+	"java":       ".java",
+	"javascript": ".js",
+	"js":         ".js",
+	"json":       ".json",
+	"kotlin":     ".kt",
+	"lua":        ".lua",
+	"makefile":   ".mk",
+	"markdown":   ".md",
+	"md":         ".md",
+	"mermaid":    ".mmd",
+	"php":        ".php",
+	"plaintext":  ".txt",
+	"powershell": ".ps1",
+	"python":     ".py",
+	"py":         ".py",
+	"ruby":       ".rb",
+	"rust":       ".rs",
+	"sh":         ".sh",
+	"shell":      ".sh",
+	"sql":        ".sql",
+	"swift":      ".swift",
+	"text":       ".txt",
+	"toml":       ".toml",
+	"ts":         ".ts",
+	"typescript": ".ts",
+	"xml":        ".xml",
+	"yaml":       ".yaml",
+	"yml":        ".yaml",
+	"zsh":        ".sh",
+}
+
+// relative filename (e.g. 'main.go', 'cmd/server/main.go')
+var codeBlockFilenamePattern = regexp.MustCompile(`^(?:[\w-][\w.-]*/)*[\w-][\w.-]*\.[A-Za-z][A-Za-z0-9]*$`)
+
+// paragraphs written by this program (sections of prompt/response markdown)
+var programSections = []string{"Prompt to Gemini:", "System Instruction to Gemini:", "Data referenced by the Prompt:",
+	"Data referenced by this Prompt only:", "Function Calls:", "Error Response from Gemini:"}
+
+/*
+nodeText gets plain text of markdown node (text of all inline children).
+*/
+func nodeText(node ast.Node, source []byte) string {
+	var nodeText strings.Builder
+	_ = ast.Walk(node, func(n ast.Node, entering bool) (ast.WalkStatus, error) {
+		if !entering {
+			return ast.WalkContinue, nil
+		}
+		switch n := n.(type) {
+		case *ast.Text:
+			nodeText.Write(n.Segment.Value(source))
+			if n.SoftLineBreak() {
+				nodeText.WriteString(" ")
+			}
+		case *ast.String:
+			nodeText.Write(n.Value)
+		}
+		return ast.WalkContinue, nil
+	})
+	return nodeText.String()
+}
+
+/*
+filenameHint finds filename in text (e.g. heading 'File `cmd/main.go`'). If knownOnly is set,
+only filenames with extension of known language are accepted (avoids e.g. 'e.g.' or 'v1.2').
+*/
+func filenameHint(text string, knownOnly bool) string {
+	for _, field := range strings.Fields(text) {
+		field = strings.Trim(field, "`'\"*()[]{},;:!?")
+		field = strings.TrimPrefix(strings.TrimPrefix(field, "title="), "file=")
+		field = strings.Trim(field, "`'\"")
+		if !codeBlockFilenamePattern.MatchString(field) {
+			continue
+		}
+		if knownOnly && !isCodeBlockExtension(filepath.Ext(field)) {
+			continue
+		}
+		return field
+	}
+	return ""
+}
+
+/*
+isCodeBlockExtension checks if extension belongs to known code block language.
+*/
+func isCodeBlockExtension(extension string) bool {
+	for _, knownExtension := range codeBlockExtensions {
+		if strings.EqualFold(extension, knownExtension) {
+			return true
+		}
+	}
+	return false
+}
+
+/*
+parseCodeBlockInfo gets language and filename hint from info string of fenced code block
+(e.g. 'go main.go', 'go:main.go', 'python title="tools/run.py"').
+*/
+func parseCodeBlockInfo(info string) (string, string) {
+	fields := strings.Fields(info)
+	if len(fields) == 0 {
+		return "", ""
+	}
+	language, hint, _ := strings.Cut(fields[0], ":")
+	if hint == "" && len(fields) > 1 {
+		hint = filenameHint(strings.Join(fields[1:], " "), false)
+	}
+	if hint == "" && codeBlockFilenamePattern.MatchString(language) {
+		// info string is filename only (e.g. 'main.go')
+		hint = language
+		language = strings.TrimPrefix(filepath.Ext(language), ".")
+	}
+	return strings.ToLower(language), hint
+}
+
+/*
+extractCodeBlocks parses markdown of prompt/response pairs and gets fenced code blocks of responses.
+*/
+func extractCodeBlocks(source []byte) []CodeBlock {
+	document := markdownParser.Parser().Parse(text.NewReader(source))
+
+	codeBlocks := []CodeBlock{}
+	inResponse := false
+	heading := ""
+	_ = ast.Walk(document, func(node ast.Node, entering bool) (ast.WalkStatus, error) {
+		if !entering {
+			return ast.WalkContinue, nil
+		}
+		switch node := node.(type) {
+		case *ast.Paragraph:
+			paragraph := strings.TrimSpace(nodeText(node, source))
+			if strings.HasPrefix(paragraph, "Response from Gemini") {
+				inResponse = true
+				heading = ""
+			}
+			for _, section := range programSections {
+				if paragraph == section {
+					inResponse = false
+				}
+			}
+			return ast.WalkSkipChildren, nil
+		case *ast.Heading:
+			heading = nodeText(node, source)
+			return ast.WalkSkipChildren, nil
+		case *ast.FencedCodeBlock:
+			var code strings.Builder
+			lines := node.Lines()
+			for i := 0; i < lines.Len(); i++ {
+				segment := lines.At(i)
+				code.Write(segment.Value(source))
+			}
+			// response metadata (footer) ends response
+			if strings.HasPrefix(code.String(), "AI model   :") {
+				inResponse = false
+			}
+			if !inResponse {
+				return ast.WalkSkipChildren, nil
+			}
+			info := ""
+			if node.Info != nil {
+				info = string(node.Info.Segment.Value(source))
+			}
+			language, hint := parseCodeBlockInfo(info)
+			codeBlock := CodeBlock{Language: language, Hint: hint, Source: "info", Code: code.String()}
+			if codeBlock.Hint == "" {
+				codeBlock.Hint = filenameHint(heading, true)
+				codeBlock.Source = "heading"
+			}
+			if codeBlock.Hint == "" {
+				codeBlock.Source = "language"
+			}
+			heading = ""
+			codeBlocks = append(codeBlocks, codeBlock)
+			return ast.WalkSkipChildren, nil
+		}
+		return ast.WalkContinue, nil
+	})
+	return codeBlocks
+}
+
+/*
+codeBlockFilename builds relative filename of code block (hint or number and language-based extension).
+*/
+func codeBlockFilename(codeBlock CodeBlock, number int, used map[string]bool) string {
+	filename := ""
+	if codeBlock.Hint != "" && filepath.IsLocal(filepath.FromSlash(codeBlock.Hint)) {
+		filename = filepath.FromSlash(codeBlock.Hint)
+	}
+	if filename == "" {
+		extension, ok := codeBlockExtensions[codeBlock.Language]
+		if !ok {
+			extension = ".txt"
+		}
+		filename = fmt.Sprintf("block-%02d%s", number, extension)
+	}
+
+	// same filename used by several code blocks (e.g. versions of file)
+	unique := filename
+	extension := filepath.Ext(filename)
+	for i := 2; used[unique]; i++ {
+		unique = fmt.Sprintf("%s-%d%s", strings.TrimSuffix(filename, extension), i, extension)
+	}
+	used[unique] = true
+	return unique
+}
+
+/*
+writeCodeBlocks writes code blocks into directory and prints manifest of written files.
+*/
+func writeCodeBlocks(codeBlocks []CodeBlock, directory string) {
+	if len(codeBlocks) == 0 {
+		return
+	}
+
+	fmt.Printf("\nExtracted code blocks (%s):\n", directory)
+	used := map[string]bool{}
+	for i, codeBlock := range codeBlocks {
+		filename := codeBlockFilename(codeBlock, i+1, used)
+		pathFile := filepath.Join(directory, filename)
+		err := os.MkdirAll(filepath.Dir(pathFile), 0750)
+		if err != nil {
+			fmt.Printf("error [%v] at os.MkdirAll()\n", err)
+			continue
+		}
+		err = os.WriteFile(pathFile, []byte(codeBlock.Code), 0644)
+		if err != nil {
+			fmt.Printf("error [%v] at os.WriteFile()\n", err)
+			continue
+		}
+		language := codeBlock.Language
+		if language == "" {
+			language = "-"
+		}
+		fmt.Printf("  %3d  %-12.12s  %5d %s  %-8s  %s\n", i+1, language, strings.Count(codeBlock.Code, "\n"),
+			pluralize(strings.Count(codeBlock.Code, "\n"), "line"), codeBlock.Source, filename)
+	}
+}
+
+/*
+extractResponseCodeBlocks writes code blocks of current response into subdirectory named like history entry.
+*/
+func extractResponseCodeBlocks(now time.Time, prompt, responseMarkdown string) {
+	codeBlocks := extractCodeBlocks([]byte(responseMarkdown))
+	directory := filepath.Join(progConfig.ExtractCodeDirectory, buildDestinationFilename(now, prompt, ""))
+	writeCodeBlocks(codeBlocks, directory)
+}
+
+/*
+extractCodeBlocksFromFiles writes code blocks of responses in markdown files (e.g. history) into subdirectories
+named like markdown files.
+*/
+func extractCodeBlocksFromFiles(filenames []string) {
+	if len(filenames) == 0 {
+		fmt.Printf("error: no markdown files given via command line\n")
+		return
+	}
+	for _, filename := range filenames {
+		source, err := os.ReadFile(filename)
+		if err != nil {
+			fmt.Printf("error [%v] reading markdown file [%s]\n", err, filename)
+			continue
+		}
+		codeBlocks := extractCodeBlocks(source)
+		if len(codeBlocks) == 0 {
+			fmt.Printf("\nNo code blocks in responses of [%s].\n", filename)
+			continue
+		}
+		base := filepath.Base(filename)
+		directory := filepath.Join(progConfig.ExtractCodeDirectory, strings.TrimSuffix(base, filepath.Ext(base)))
+		writeCodeBlocks(codeBlocks, directory)
+	}
+}
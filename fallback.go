@@ -0,0 +1,191 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/google/generative-ai-go/genai"
+)
+
+// error classes which can trigger fallback to next AI model
+var fallbackErrorClasses = []string{"quota", "overloaded", "notfound", "internal", "timeout", "blocked", "other"}
+
+// FallbackModel represents a configured fallback AI model
+type FallbackModel struct {
+	model *genai.GenerativeModel
+	info  *genai.ModelInfo
+}
+
+// fallback AI models (created on first use)
+var fallbackModels = map[string]*FallbackModel{}
+
+// information about AI model which generated last response
+var responseModelInfo *genai.ModelInfo
+
+/*
+getFallbackModel gets fallback AI model with same configuration as primary AI model.
+*/
+func getFallbackModel(ctx context.Context, client *genai.Client, geminiModel *genai.GenerativeModel, name string) (*FallbackModel, error) {
+	if fallbackModel, ok := fallbackModels[name]; ok {
+		return fallbackModel, nil
+	}
+
+	model := client.GenerativeModel(name)
+	info, err := model.Info(ctx)
+	if err != nil {
+		return nil, err
+	}
+	model.GenerationConfig = geminiModel.GenerationConfig
+	model.SafetySettings = geminiModel.SafetySettings
+	model.Tools = geminiModel.Tools
+	model.ToolConfig = geminiModel.ToolConfig
+	model.SystemInstruction = geminiModel.SystemInstruction
+	if contextCache != nil {
+		// context cache is bound to primary model
+		model.Tools = contextCacheContent.Tools
+		model.ToolConfig = contextCacheContent.ToolConfig
+		model.SystemInstruction = contextCacheContent.Instruction
+	}
+
+	fallbackModel := &FallbackModel{model: model, info: info}
+	fallbackModels[name] = fallbackModel
+	return fallbackModel, nil
+}
+
+/*
+generateWithFallback generates content with primary AI model and, on configured error classes, with fallback models.
+Each model is tried according to retry policy (AI model of response is set in responseModelInfo).
+Estimated prompt tokens are used for client-side rate limiting.
+*/
+func generateWithFallback(ctx context.Context, client *genai.Client, geminiModel *genai.GenerativeModel, promptParts []genai.Part, promptTokens int32) (*genai.GenerateContentResponse, error) {
+	var resp *genai.GenerateContentResponse
+	var err error
+
+	generationAttempts = 0
+	functionCallRecords = nil
+	models := append([]string{progConfig.GeminiAiModel}, progConfig.GeminiFallbackModels...)
+	for i, name := range models {
+		model := geminiModel
+		responseModelInfo = modelInfo
+		if i > 0 {
+			fallbackModel, err := getFallbackModel(ctx, client, geminiModel, name)
+			if err != nil {
+				fmt.Printf("error [%v] getting fallback AI model [%s]\n", err, name)
+				continue
+			}
+			model = fallbackModel.model
+			responseModelInfo = fallbackModel.info
+		}
+
+		parts := promptParts
+		if i > 0 && contextCache != nil {
+			// fallback model can't use context cache of primary model (attached files are sent with prompt)
+			parts = append(append([]genai.Part{}, contextCacheContent.Parts...), promptParts...)
+		}
+		resp, err = generateWithModel(ctx, model, name, i > 0, parts, promptTokens)
+		if err == nil || !slices.Contains(progConfig.GeminiFallbackErrorClasses, classifyError(err)) {
+			return resp, err
+		}
+		if i < len(models)-1 {
+			fmt.Printf("error [%v] generating content with AI model [%s] (class %s), falling back to AI model [%s] ...\n",
+				err, name, classifyError(err), models[i+1])
+		}
+	}
+
+	return resp, err
+}
+
+/*
+generateWithModel generates content with given AI model (streaming, chat or single prompt).
+In chat mode, a fallback model continues the conversation with the history of the current chat.
+Function calls requested by AI model are executed and answered until a final response arrives.
+Each request (incl. retries and function call rounds) is subject to client-side rate limits of the AI model.
+*/
+func generateWithModel(ctx context.Context, model *genai.GenerativeModel, name string, fallback bool, promptParts []genai.Part, promptTokens int32) (*genai.GenerateContentResponse, error) {
+	if chatSession != nil && fallback {
+		primarySession := chatSession
+		chatSession = model.StartChat()
+		chatSession.History = primarySession.History
+		defer func() {
+			primarySession.History = chatSession.History
+			chatSession = primarySession
+		}()
+	}
+	historyLength := 0
+	if chatSession != nil {
+		historyLength = len(chatSession.History)
+	}
+
+	var resp *genai.GenerateContentResponse
+	var usageMetadata *genai.UsageMetadata
+	var err error
+	parts := promptParts
+	for round := 0; ; round++ {
+		var attempts int
+		attempts, err = withRetry(ctx, "generating content", func() error {
+			// client-side rate limiting (prompt waits in queue until limits allow sending)
+			waitForRateLimit(name, int(promptTokens))
+			start := time.Now()
+			var err error
+			switch {
+			case progConfig.GeminiStreamResponse:
+				resp, err = generateContentStream(ctx, model, parts)
+			case chatSession != nil:
+				resp, err = sendChatMessage(ctx, parts)
+			default:
+				resp, err = model.GenerateContent(ctx, parts...)
+			}
+			recordRateLimitRequest(name, start, int(promptTokens), resp, err)
+			if progConfig.UsageLedger {
+				appendUsageRecord(name, start, resp, err)
+			}
+			return err
+		})
+		generationAttempts += attempts
+		if err != nil {
+			break
+		}
+		usageMetadata = addUsageMetadata(usageMetadata, resp.UsageMetadata)
+
+		calls := responseFunctionCalls(resp)
+		if len(calls) == 0 {
+			break
+		}
+		if round >= progConfig.FunctionCallMaxRounds {
+			err = fmt.Errorf("function call limit reached (%d rounds)", progConfig.FunctionCallMaxRounds)
+			break
+		}
+		if chatSession == nil {
+			// function calls need conversation (call and response), temporary chat without chat mode;
+			// first request is sent without chat, so candidate count is respected if no function is called
+			if len(resp.Candidates) > 1 {
+				fmt.Printf("warning: function calls requested, continuing with first of %d candidates (chat supports one candidate only)\n",
+					len(resp.Candidates))
+			}
+			chatSession = model.StartChat()
+			chatSession.History = []*genai.Content{genai.NewUserContent(promptParts...),
+				{Role: "model", Parts: resp.Candidates[0].Content.Parts}}
+			defer func() {
+				chatSession = nil
+			}()
+		}
+		parts = executeFunctionCalls(ctx, calls)
+	}
+
+	// chat history keeps one prompt/response pair per turn (function calls are logged in transcript only)
+	if chatSession != nil && len(chatSession.History) > historyLength+2 {
+		history := chatSession.History
+		chatSession.History = append(history[:historyLength+1], history[len(history)-1])
+	}
+	if err != nil {
+		if chatSession != nil && len(chatSession.History) > historyLength {
+			chatSession.History = chatSession.History[:historyLength]
+		}
+		return nil, err
+	}
+	resp.UsageMetadata = usageMetadata
+
+	return resp, err
+}
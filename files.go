@@ -0,0 +1,224 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/google/generative-ai-go/genai"
+)
+
+// prefix of names of remote files uploaded by this program (e.g. 'files/gemini-prompt-4f2a9c01b7e3')
+const remoteFilePrefix = "gemini-prompt-"
+
+/*
+uploadFile uploads local file as remote file named with program prefix (identifies files uploaded by this program).
+*/
+func uploadFile(ctx context.Context, client *genai.Client, filename string, uploadOptions *genai.UploadFileOptions) (*genai.File, error) {
+	id := make([]byte, 6)
+	_, err := rand.Read(id)
+	if err != nil {
+		return nil, err
+	}
+
+	file, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	return client.UploadFile(ctx, remoteFilePrefix+hex.EncodeToString(id), file, uploadOptions)
+}
+
+/*
+isProgramRemoteFile checks if remote file was uploaded by this program.
+*/
+func isProgramRemoteFile(file *genai.File) bool {
+	return strings.HasPrefix(strings.TrimPrefix(file.Name, "files/"), remoteFilePrefix)
+}
+
+/*
+manageRemoteFiles handles remote file management (list, show, delete, purge).
+*/
+func manageRemoteFiles(action string, arguments []string) {
+	ctx := context.Background()
+	client, err := createClient(ctx)
+	if err != nil {
+		fmt.Printf("error [%v] creating AI client\n", err)
+		return
+	}
+	defer client.Close()
+
+	if progConfig.UploadCache {
+		uploadCache = loadUploadCache()
+	}
+
+	switch action {
+	case "list":
+		listRemoteFileTable(ctx, client)
+	case "show":
+		if len(arguments) == 0 {
+			fmt.Printf("error: name of remote file missing (e.g. '-files show files/abc-123')\n")
+			return
+		}
+		for _, name := range arguments {
+			showRemoteFile(ctx, client, name)
+		}
+	case "delete":
+		if len(arguments) == 0 {
+			fmt.Printf("error: name or pattern of remote files missing (e.g. '-files delete \"files/abc-*\"')\n")
+			return
+		}
+		deleteRemoteFiles(ctx, client, func(file *genai.File) bool {
+			for _, pattern := range arguments {
+				if matchRemoteFile(pattern, file) {
+					return true
+				}
+			}
+			return false
+		})
+	case "purge":
+		deleteRemoteFiles(ctx, client, isProgramRemoteFile)
+	default:
+		fmt.Printf("error: unsupported files action [%s] (not 'list', 'show', 'delete' or 'purge')\n", action)
+	}
+}
+
+/*
+sortedRemoteFiles lists all remote files sorted by creation time.
+*/
+func sortedRemoteFiles(ctx context.Context, client *genai.Client) ([]*genai.File, error) {
+	remoteFiles, err := listRemoteFiles(ctx, client)
+	files := []*genai.File{}
+	for _, file := range remoteFiles {
+		files = append(files, file)
+	}
+	sort.Slice(files, func(i, j int) bool {
+		return files[i].CreateTime.Before(files[j].CreateTime)
+	})
+	return files, err
+}
+
+/*
+listRemoteFileTable prints table of all remote files.
+*/
+func listRemoteFileTable(ctx context.Context, client *genai.Client) {
+	files, err := sortedRemoteFiles(ctx, client)
+	if err != nil {
+		fmt.Printf("error [%v] listing remote files\n", err)
+	}
+
+	fmt.Printf("\nRemote files:\n")
+	if len(files) == 0 {
+		fmt.Printf("  no remote files found\n\n")
+		return
+	}
+	totalSize := int64(0)
+	for _, file := range files {
+		owner := " "
+		if isProgramRemoteFile(file) {
+			owner = "*"
+		}
+		fmt.Printf("  %s %-34s  %-10s  %12s  %-28.28s  %-24s  %s\n", owner, file.Name, file.State.String(),
+			fmt.Sprintf("%.1f KiB", float64(file.SizeBytes)/1024.0), file.MIMEType, formatExpiration(file.ExpirationTime), file.DisplayName)
+		totalSize += file.SizeBytes
+	}
+	fmt.Printf("\nTotal: %d %s (%.1f KiB), * = uploaded by %s\n\n", len(files), pluralize(len(files), "file"),
+		float64(totalSize)/1024.0, progName)
+}
+
+/*
+showRemoteFile prints details of remote file.
+*/
+func showRemoteFile(ctx context.Context, client *genai.Client, name string) {
+	file, err := client.GetFile(ctx, name)
+	if err != nil {
+		fmt.Printf("error [%v] getting remote file [%s]\n", err, name)
+		return
+	}
+
+	fmt.Printf("\nRemote file:\n")
+	fmt.Printf("  Name        : %s\n", file.Name)
+	fmt.Printf("  DisplayName : %s\n", file.DisplayName)
+	fmt.Printf("  URI         : %s\n", file.URI)
+	fmt.Printf("  MIMEType    : %s\n", file.MIMEType)
+	fmt.Printf("  Size        : %.1f KiB (%d bytes)\n", float64(file.SizeBytes)/1024.0, file.SizeBytes)
+	fmt.Printf("  State       : %s\n", file.State.String())
+	fmt.Printf("  Created     : %s\n", file.CreateTime.Local().Format("2006-01-02 15:04:05"))
+	fmt.Printf("  Updated     : %s\n", file.UpdateTime.Local().Format("2006-01-02 15:04:05"))
+	fmt.Printf("  Expires     : %s\n", formatExpiration(file.ExpirationTime))
+	fmt.Printf("  SHA-256     : %s\n", hex.EncodeToString(file.Sha256Hash))
+	if file.Metadata != nil && file.Metadata.Video != nil {
+		fmt.Printf("  Video       : %v\n", file.Metadata.Video.Duration)
+	}
+	if file.Error != nil {
+		fmt.Printf("  Error       : %v\n", file.Error)
+	}
+	if isProgramRemoteFile(file) {
+		fmt.Printf("  Uploaded by : %s\n", progName)
+	}
+	fmt.Printf("\n")
+}
+
+/*
+deleteRemoteFiles deletes all remote files selected by given function.
+*/
+func deleteRemoteFiles(ctx context.Context, client *genai.Client, selected func(*genai.File) bool) {
+	files, err := sortedRemoteFiles(ctx, client)
+	if err != nil {
+		fmt.Printf("error [%v] listing remote files\n", err)
+	}
+
+	fmt.Printf("\nDeleting remote files:\n")
+	deleted := 0
+	for _, file := range files {
+		if !selected(file) {
+			continue
+		}
+		fmt.Printf("  %s (%s) ... ", file.Name, file.DisplayName)
+		err = client.DeleteFile(ctx, file.Name)
+		if err != nil {
+			fmt.Printf("error: %v\n", err)
+			continue
+		}
+		fmt.Printf("deleted\n")
+		deleted++
+		if uploadCache != nil {
+			uploadCache.remove(file.Name)
+		}
+	}
+	if uploadCache != nil {
+		uploadCache.save()
+	}
+	fmt.Printf("\nDeleted: %d %s\n\n", deleted, pluralize(deleted, "file"))
+}
+
+/*
+matchRemoteFile checks if remote file matches name or pattern (name with or without 'files/' or display name).
+*/
+func matchRemoteFile(pattern string, file *genai.File) bool {
+	for _, name := range []string{file.Name, strings.TrimPrefix(file.Name, "files/"), file.DisplayName} {
+		matched, err := path.Match(pattern, name)
+		if err == nil && matched {
+			return true
+		}
+	}
+	return false
+}
+
+/*
+formatExpiration formats expiration time of remote file (incl. remaining time).
+*/
+func formatExpiration(expiration time.Time) string {
+	if expiration.IsZero() {
+		return "-"
+	}
+	remaining := time.Until(expiration).Round(time.Minute)
+	return fmt.Sprintf("%s (%v)", expiration.Local().Format("01-02 15:04"), remaining)
+}
@@ -0,0 +1,254 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os/exec"
+	"regexp"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/google/generative-ai-go/genai"
+)
+
+// FunctionTool represents a function declared in YAML configuration (executed locally on request of AI model)
+type FunctionTool struct {
+	Name        string         `yaml:"Name"`
+	Description string         `yaml:"Description"`
+	Parameters  map[string]any `yaml:"Parameters"` // JSON schema of arguments (type object)
+	Command     string         `yaml:"Command"`    // command template (e.g. 'git log -n {{.count}}'), no shell
+	URL         string         `yaml:"URL"`        // HTTP endpoint (arguments are posted as JSON object)
+	Confirm     bool           `yaml:"Confirm"`    // ask user before execution
+}
+
+// FunctionCallRecord represents executed (or declined) function call of current prompt (logged in transcript)
+type FunctionCallRecord struct {
+	Name     string
+	Args     map[string]any
+	Response map[string]any
+	Duration time.Duration
+}
+
+// function calls of current prompt
+var functionCallRecords []FunctionCallRecord
+
+// valid function name (required by Gemini API)
+var functionNamePattern = regexp.MustCompile(`^[a-zA-Z_][a-zA-Z0-9_-]{0,62}$`)
+
+/*
+buildFunctionTool builds tool with declarations of all configured functions.
+*/
+func buildFunctionTool() (*genai.Tool, error) {
+	tool := &genai.Tool{}
+	for _, function := range progConfig.FunctionTools {
+		declaration := &genai.FunctionDeclaration{Name: function.Name, Description: function.Description}
+		if len(function.Parameters) > 0 {
+			schema, err := schemaFromMap(function.Parameters)
+			if err != nil {
+				return nil, fmt.Errorf("function [%s]: %w", function.Name, err)
+			}
+			declaration.Parameters = schema
+		}
+		tool.FunctionDeclarations = append(tool.FunctionDeclarations, declaration)
+	}
+	return tool, nil
+}
+
+/*
+findFunctionTool finds configured function by name.
+*/
+func findFunctionTool(name string) (FunctionTool, bool) {
+	for _, function := range progConfig.FunctionTools {
+		if function.Name == name {
+			return function, true
+		}
+	}
+	return FunctionTool{}, false
+}
+
+/*
+responseFunctionCalls gets function calls requested by AI model (first candidate).
+*/
+func responseFunctionCalls(resp *genai.GenerateContentResponse) []genai.FunctionCall {
+	calls := []genai.FunctionCall{}
+	if resp == nil || len(resp.Candidates) == 0 || resp.Candidates[0].Content == nil {
+		return calls
+	}
+	for _, part := range resp.Candidates[0].Content.Parts {
+		if call, ok := part.(genai.FunctionCall); ok {
+			calls = append(calls, call)
+		}
+	}
+	return calls
+}
+
+/*
+executeFunctionCalls executes function calls (optionally after confirmation) and returns function responses.
+*/
+func executeFunctionCalls(ctx context.Context, calls []genai.FunctionCall) []genai.Part {
+	parts := []genai.Part{}
+	for _, call := range calls {
+		start := time.Now()
+		response := executeFunctionCall(ctx, call)
+		functionCallRecords = append(functionCallRecords, FunctionCallRecord{
+			Name:     call.Name,
+			Args:     call.Args,
+			Response: response,
+			Duration: time.Since(start),
+		})
+		parts = append(parts, genai.FunctionResponse{Name: call.Name, Response: response})
+	}
+	return parts
+}
+
+/*
+executeFunctionCall executes single function call (command or HTTP request). Errors are reported to AI model.
+*/
+func executeFunctionCall(ctx context.Context, call genai.FunctionCall) map[string]any {
+	args, _ := json.Marshal(call.Args)
+	fmt.Printf("function call [%s(%s)] requested by AI model\n", call.Name, args)
+
+	function, ok := findFunctionTool(call.Name)
+	if !ok {
+		return map[string]any{"error": fmt.Sprintf("unknown function [%s]", call.Name)}
+	}
+	if function.Confirm {
+		question := fmt.Sprintf("Execute function [%s(%s)]?", call.Name, args)
+		if !askForConfirmation(question) {
+			return map[string]any{"error": "function call declined by user"}
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, time.Duration(progConfig.FunctionCallTimeout)*time.Second)
+	defer cancel()
+
+	if function.URL != "" {
+		return callFunctionURL(ctx, function, call.Args)
+	}
+	return runFunctionCommand(ctx, function, call.Args)
+}
+
+/*
+runFunctionCommand runs command of function (each template argument is expanded separately, no shell).
+*/
+func runFunctionCommand(ctx context.Context, function FunctionTool, args map[string]any) map[string]any {
+	// optional arguments not given by AI model are empty
+	values := map[string]any{}
+	if properties, ok := function.Parameters["properties"].(map[string]any); ok {
+		for name := range properties {
+			values[name] = ""
+		}
+	}
+	for name, value := range args {
+		values[name] = value
+	}
+
+	commandArgs := []string{}
+	for _, commandArg := range splitCommandLine(function.Command) {
+		tmpl, err := template.New(function.Name).Option("missingkey=error").Parse(commandArg)
+		if err != nil {
+			return map[string]any{"error": fmt.Sprintf("invalid command template: %v", err)}
+		}
+		var expanded strings.Builder
+		err = tmpl.Execute(&expanded, values)
+		if err != nil {
+			return map[string]any{"error": fmt.Sprintf("expanding command template: %v", err)}
+		}
+		commandArgs = append(commandArgs, expanded.String())
+	}
+	if len(commandArgs) == 0 {
+		return map[string]any{"error": "empty command"}
+	}
+
+	output, err := exec.CommandContext(ctx, commandArgs[0], commandArgs[1:]...).CombinedOutput()
+	response := map[string]any{"output": string(output)}
+	var exitError *exec.ExitError
+	switch {
+	case errors.As(err, &exitError):
+		response["exitCode"] = exitError.ExitCode()
+	case err != nil:
+		response["error"] = err.Error()
+	default:
+		response["exitCode"] = 0
+	}
+	return response
+}
+
+/*
+callFunctionURL posts arguments of function call as JSON object to HTTP endpoint.
+JSON object responses are returned as they are, other responses as text output.
+*/
+func callFunctionURL(ctx context.Context, function FunctionTool, args map[string]any) map[string]any {
+	body, err := json.Marshal(args)
+	if err != nil {
+		return map[string]any{"error": err.Error()}
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, function.URL, bytes.NewReader(body))
+	if err != nil {
+		return map[string]any{"error": err.Error()}
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return map[string]any{"error": err.Error()}
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return map[string]any{"error": err.Error()}
+	}
+
+	response := map[string]any{}
+	if json.Unmarshal(data, &response) != nil {
+		response = map[string]any{"output": string(data)}
+	}
+	if resp.StatusCode >= 300 {
+		response["status"] = resp.Status
+	}
+	return response
+}
+
+/*
+formatFunctionCalls formats function calls of current prompt as markdown (transcript).
+*/
+func formatFunctionCalls() string {
+	if len(functionCallRecords) == 0 {
+		return ""
+	}
+
+	var calls strings.Builder
+	calls.WriteString("**Function Calls:**\n\n")
+	for i, record := range functionCallRecords {
+		args, _ := json.Marshal(record.Args)
+		response, _ := json.MarshalIndent(record.Response, "", "  ")
+		calls.WriteString(fmt.Sprintf("%d. `%s(%s)` (%.1f secs)\n\n", i+1, record.Name, args, record.Duration.Seconds()))
+		calls.WriteString(fmt.Sprintf("```json\n%s\n```\n\n", response))
+	}
+	calls.WriteString("***\n")
+	return calls.String()
+}
+
+/*
+addUsageMetadata adds token counts of intermediate response (function call round) to final response.
+*/
+func addUsageMetadata(total *genai.UsageMetadata, usage *genai.UsageMetadata) *genai.UsageMetadata {
+	if usage == nil {
+		return total
+	}
+	if total == nil {
+		sum := *usage
+		return &sum
+	}
+	total.PromptTokenCount += usage.PromptTokenCount
+	total.CachedContentTokenCount += usage.CachedContentTokenCount
+	total.CandidatesTokenCount += usage.CandidatesTokenCount
+	total.TotalTokenCount += usage.TotalTokenCount
+	return total
+}
@@ -2,72 +2,68 @@ package main
 
 import (
 	"context"
-	"fmt"
-	"time"
+	"errors"
+	"net/http"
 
 	"github.com/google/generative-ai-go/genai"
+	"google.golang.org/api/googleapi"
+	"google.golang.org/api/option"
 )
 
 /*
-uploadFilesToGemini uploads all files given from command line.
+createClient creates AI client (direct or via internet proxy).
 */
-func uploadFilesToGemini(ctx context.Context, client *genai.Client, clFiles []string) ([]*genai.File, error) {
-	var err error
-
-	files := []*genai.File{}
-	if len(clFiles) == 0 {
-		return files, nil
+func createClient(ctx context.Context) (*genai.Client, error) {
+	if progConfig.GeneralInternetProxy != "" {
+		// indirect internet connection: client -> proxy -> internet
+		httpClient := &http.Client{Transport: &ProxyRoundTripper{
+			APIKey:   progConfig.GeminiAPIKey,
+			ProxyURL: progConfig.GeneralInternetProxy,
+		}}
+		// option.WithAPIKey() shouldn't be necessary because the key is set in ProxyRoundTripper
+		// but without the option, NewClient() attempts to authenticate via Google Cloud SDK (ADC)
+		return genai.NewClient(ctx, option.WithAPIKey(progConfig.GeminiAPIKey), option.WithHTTPClient(httpClient))
 	}
 
-	fmt.Printf("\nFile uploads:\n")
-	for _, filename := range clFiles {
-		fmt.Printf("  %s ... ", filename)
-		if !fileExists(filename) {
-			fmt.Printf("error: file does't exist\n")
-			continue
-		}
+	// direct internet connection: client -> internet
+	return genai.NewClient(ctx, option.WithAPIKey(progConfig.GeminiAPIKey))
+}
 
-		// display name = max 512 characters
-		uploadOptions := genai.UploadFileOptions{}
-		uploadOptions.DisplayName = filename
+/*
+classifyError classifies error returned from Gemini AI (e.g. 'quota', 'overloaded', 'blocked').
+*/
+func classifyError(err error) string {
+	if err == nil {
+		return "none"
+	}
 
-		file, err := client.UploadFileFromPath(ctx, filename, &uploadOptions)
-		if err != nil {
-			fmt.Printf("error: %v\n", err)
-		} else {
-			fmt.Printf("uploaded (%.1f KiB, %s)\n", float64(file.SizeBytes)/1024.0, file.MIMEType)
-			files = append(files, file)
-		}
+	var blockedError *genai.BlockedError
+	if errors.As(err, &blockedError) {
+		return "blocked"
+	}
+	if errors.Is(err, context.DeadlineExceeded) {
+		return "timeout"
 	}
 
-	// ensure that all remote (uploaded) files have state "active" (e.g. videos need to be processed)
-	fmt.Printf("\nRemote file states:\n")
-	maxWaitDuration := progConfig.GeminiMaxWaitTimeFileProcessing // seconds
-	for i, file := range files {
-		currentWaitDuration := 0
-		tmpFile := file
-		for tmpFile.State == genai.FileStateProcessing {
-			tmpFile, err = client.GetFile(ctx, file.Name)
-			if err != nil {
-				fmt.Printf("  error [%s] getting state for remote file [%s]\n", err, file.DisplayName)
-				break
-			}
-			if tmpFile.State == genai.FileStateActive {
-				break
-			}
-			if tmpFile.State != genai.FileStateProcessing {
-				break
-			}
-			time.Sleep(3 * time.Second)
-			currentWaitDuration += 3
-			if currentWaitDuration >= maxWaitDuration {
-				break
-			}
-			fmt.Printf(".")
+	var apiError *googleapi.Error
+	if errors.As(err, &apiError) {
+		switch apiError.Code {
+		case http.StatusBadRequest:
+			return "invalid"
+		case http.StatusForbidden:
+			return "permission"
+		case http.StatusNotFound:
+			return "notfound"
+		case http.StatusTooManyRequests:
+			return "quota"
+		case http.StatusInternalServerError:
+			return "internal"
+		case http.StatusServiceUnavailable:
+			return "overloaded"
+		case http.StatusGatewayTimeout:
+			return "timeout"
 		}
-		files[i] = tmpFile
-		fmt.Printf("\r  %s ... %s\n", tmpFile.DisplayName, tmpFile.State.String())
 	}
 
-	return files, nil
+	return "other"
 }
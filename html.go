@@ -20,7 +20,7 @@ func renderMarkdown2HTML(md string) string {
 	}
 
 	// replace HTML elements
-	htmlDataModified := string(buf.String())
+	htmlDataModified := embedMediaLinks(collapseSafetyRatings(buf.String()))
 	for _, item := range progConfig.HTMLReplaceElements {
 		for key, value := range item {
 			htmlDataModified = strings.ReplaceAll(htmlDataModified, key, value)
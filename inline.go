@@ -0,0 +1,170 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/google/generative-ai-go/genai"
+)
+
+// InlineFile represents a local file embedded directly into prompts (no upload via File API)
+type InlineFile struct {
+	Filename string
+	MIMEType string
+	Size     int64
+	Parts    []genai.Part // header and content (text or blob)
+}
+
+// files embedded into all prompts (attachment set, like uploadedFiles)
+var inlinedFiles []*InlineFile
+
+/*
+isInlineCandidate checks if local file should be embedded into prompt instead of being uploaded.
+Returns true for files below UploadInlineMaxSize and text-like files below UploadInlineTextMaxSize.
+*/
+func isInlineCandidate(filename string) bool {
+	info, err := os.Stat(filename)
+	if err != nil || !info.Mode().IsRegular() {
+		return false
+	}
+	if info.Size() <= progConfig.UploadInlineMaxSize {
+		return true
+	}
+	return progConfig.UploadInlineTextFiles && info.Size() <= progConfig.UploadInlineTextMaxSize && isTextFile(filename)
+}
+
+/*
+isInlineSize checks if data of given size and kind (e.g. converted document) can be embedded into prompt.
+*/
+func isInlineSize(size int64, text bool) bool {
+	if size <= progConfig.UploadInlineMaxSize {
+		return true
+	}
+	return text && progConfig.UploadInlineTextFiles && size <= progConfig.UploadInlineTextMaxSize
+}
+
+/*
+isTextFile checks if local file is text-like (text mime type or valid UTF-8 without NUL bytes).
+*/
+func isTextFile(filename string) bool {
+	mimeType, err := getMimeType(filename)
+	if err != nil {
+		return false
+	}
+	if isTextMimeType(mimeType) {
+		return true
+	}
+
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return false
+	}
+	return utf8.Valid(data) && !strings.ContainsRune(string(data), 0)
+}
+
+/*
+isTextMimeType checks if mime type denotes text (e.g. 'text/plain', 'application/json').
+*/
+func isTextMimeType(mimeType string) bool {
+	mimeType, _, _ = strings.Cut(mimeType, ";")
+	if strings.HasPrefix(mimeType, "text/") {
+		return true
+	}
+	switch mimeType {
+	case "application/json", "application/xml", "application/yaml", "application/x-yaml", "application/javascript",
+		"application/x-sh", "application/sql", "application/toml":
+		return true
+	}
+	return false
+}
+
+/*
+loadInlineFile reads local file and builds prompt parts (header with filename and content as text or blob).
+*/
+func loadInlineFile(filename string) (*InlineFile, error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	mimeType, err := getMimeType(filename)
+	if err != nil {
+		return nil, err
+	}
+
+	inlineFile := &InlineFile{Filename: filename, MIMEType: mimeType, Size: int64(len(data))}
+	header := inlineFileHeader(filename, mimeType)
+	if isTextMimeType(mimeType) || (utf8.Valid(data) && !strings.ContainsRune(string(data), 0)) {
+		text := fmt.Sprintf("%s\n```\n%s\n```\n--- end of file: %s ---\n", header, strings.TrimRight(string(data), "\n"), filepath.ToSlash(filename))
+		inlineFile.Parts = []genai.Part{genai.Text(text)}
+	} else {
+		inlineFile.Parts = []genai.Part{genai.Text(header), genai.Blob{MIMEType: mimeType, Data: data}}
+	}
+	return inlineFile, nil
+}
+
+/*
+inlineFileHeader builds header of inlined file (identifies file in prompt).
+*/
+func inlineFileHeader(filename, mimeType string) string {
+	return fmt.Sprintf("--- file: %s (%s) ---", filepath.ToSlash(filename), mimeType)
+}
+
+/*
+isInlineFileInChatHistory checks if inlined file (same content) is already part of current chat history.
+*/
+func isInlineFileInChatHistory(inlineFile *InlineFile) bool {
+	content := inlineFile.Parts[len(inlineFile.Parts)-1]
+	for _, historyContent := range chatSession.History {
+		for _, part := range historyContent.Parts {
+			switch historyPart := part.(type) {
+			case genai.Text:
+				if text, ok := content.(genai.Text); ok && historyPart == text {
+					return true
+				}
+			case genai.Blob:
+				if blob, ok := content.(genai.Blob); ok && historyPart.MIMEType == blob.MIMEType && bytes.Equal(historyPart.Data, blob.Data) {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
+
+/*
+prepareFiles converts documents, embeds small and text-like files and uploads all other files.
+*/
+func prepareFiles(ctx context.Context, client *genai.Client, filenames []string) ([]*genai.File, []*InlineFile, error) {
+	filenames = convertDocuments(filenames)
+
+	inlineFiles := []*InlineFile{}
+	uploadFilenames := []string{}
+	for _, filename := range filenames {
+		if !isInlineCandidate(filename) {
+			uploadFilenames = append(uploadFilenames, filename)
+			continue
+		}
+		inlineFile, err := loadInlineFile(filename)
+		if err != nil {
+			fmt.Printf("error [%v] reading file [%s], uploading instead\n", err, filename)
+			uploadFilenames = append(uploadFilenames, filename)
+			continue
+		}
+		inlineFiles = append(inlineFiles, inlineFile)
+	}
+
+	if len(inlineFiles) > 0 {
+		fmt.Printf("\nInlined files:\n")
+		for _, inlineFile := range inlineFiles {
+			fmt.Printf("  %s ... inlined (%.1f KiB, %s)\n", inlineFile.Filename, float64(inlineFile.Size)/1024.0, inlineFile.MIMEType)
+		}
+	}
+
+	files, err := uploadFilesToGemini(ctx, client, uploadFilenames)
+	return files, inlineFiles, err
+}
@@ -7,13 +7,29 @@ import (
 	"net/http"
 	"os"
 	"strings"
+	"sync/atomic"
 	"time"
 )
 
+// answers to confirmation questions (read from terminal only, never from other input channels)
+var confirmationChannel = make(chan string)
+
+// confirmation question waiting for answer from terminal
+var confirmationPending atomic.Bool
+
 /*
-readPromptFromKeyboard reads prompt from keyboard (Stdin).
+readPromptFromKeyboard reads prompt from keyboard (Stdin). While a confirmation question is pending,
+input is passed as answer (prompts are queued, so the keyboard stays responsive while prompts wait).
 */
 func readPromptFromKeyboard(promptChannel chan string) {
+	terminalPrompts := make(chan string, 64)
+	go func() {
+		for prompt := range terminalPrompts {
+			promptChannel <- prompt
+			pendingPrompts.Add(-1)
+		}
+	}()
+
 	reader := bufio.NewReader(os.Stdin)
 	for {
 		promptData, err := reader.ReadString('\n')
@@ -24,6 +40,10 @@ func readPromptFromKeyboard(promptChannel chan string) {
 		if promptData == "\n" || promptData == "\r\n" {
 			continue
 		}
+		if confirmationPending.Load() {
+			confirmationChannel <- promptData
+			continue
+		}
 
 		// read prompt from given text file (e.g. "<<<MyQuery.txt" or "<<< MyQuery.txt")
 		var fileData []byte
@@ -35,10 +55,12 @@ func readPromptFromKeyboard(promptChannel chan string) {
 				continue
 			}
 			if len(fileData) > 0 {
-				promptChannel <- string(fileData)
+				pendingPrompts.Add(1)
+				terminalPrompts <- string(fileData)
 			}
 		} else {
-			promptChannel <- promptData
+			pendingPrompts.Add(1)
+			terminalPrompts <- promptData
 		}
 	}
 }
@@ -62,7 +84,7 @@ func readPromptFromFile(filePath string, promptChannel chan string) {
 				fmt.Printf("error [%v] at os.ReadFile()", err)
 			}
 			if len(promptData) > 0 {
-				promptChannel <- string(promptData)
+				submitPrompt(promptChannel, string(promptData))
 			}
 			currentStat = stat
 		}
@@ -71,11 +93,58 @@ func readPromptFromFile(filePath string, promptChannel chan string) {
 }
 
 /*
-readPromptFromLocalhost reads prompt (user input) from localhost.
+isCrossOriginRequest checks if request has been sent by browser on behalf of another origin (e.g. web page).
+Requests without browser headers (e.g. curl) and same-origin requests (localhost port of this program) are accepted.
+*/
+func isCrossOriginRequest(r *http.Request) bool {
+	switch r.Header.Get("Sec-Fetch-Site") {
+	case "", "same-origin", "none":
+	default:
+		return true
+	}
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		return false
+	}
+	for _, host := range []string{"localhost", "127.0.0.1", "[::1]"} {
+		if origin == fmt.Sprintf("http://%s:%d", host, progConfig.InputLocalhostPort) {
+			return false
+		}
+	}
+	return true
+}
+
+/*
+checkRemotePrompt checks prompt received via localhost (any local process can send such prompts): files of attach
+commands and directives outside of working directory are refused.
+*/
+func checkRemotePrompt(prompt string) error {
+	fields := strings.Fields(prompt)
+	if len(fields) > 0 && fields[0] == "/attach" {
+		for _, argument := range fields[1:] {
+			if !isInsideWorkingDirectory(argument) {
+				return fmt.Errorf("file '%s' outside of working directory not allowed in commands received via localhost", argument)
+			}
+		}
+	}
+	return checkRemotePromptDirectives(prompt)
+}
+
+/*
+readPromptFromLocalhost reads prompt (user input) from localhost. The prompt input page is served at '/'
+(same origin), cross-origin requests (e.g. from other web pages) are refused, no CORS headers are sent.
 */
 func readPromptFromLocalhost(promptChannel chan string) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		w.Header().Set("Access-Control-Allow-Origin", "*")
+		if r.Method == http.MethodGet && r.URL.Path == "/" {
+			http.ServeFile(w, r, "prompt-input.html")
+			return
+		}
+		if isCrossOriginRequest(r) {
+			http.Error(w, "cross-origin request not allowed", http.StatusForbidden)
+			fmt.Printf("error: cross-origin request [%s %s] from [%s] refused\n", r.Method, r.URL.Path, r.Header.Get("Origin"))
+			return
+		}
 
 		body, err := io.ReadAll(r.Body)
 		if err != nil {
@@ -88,7 +157,13 @@ func readPromptFromLocalhost(promptChannel chan string) http.HandlerFunc {
 			http.Error(w, "prompt empty", http.StatusBadRequest)
 			return
 		}
-		promptChannel <- string(body)
+		err = checkRemotePrompt(string(body))
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusForbidden)
+			fmt.Printf("error [%v], prompt refused\n", err)
+			return
+		}
+		submitPrompt(promptChannel, string(body))
 		defer r.Body.Close()
 
 		fmt.Fprintln(w, "prompt received")
@@ -0,0 +1,200 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/google/generative-ai-go/genai"
+)
+
+// UsageRecord represents one request to Gemini AI in usage ledger (one JSON object per line)
+type UsageRecord struct {
+	Timestamp     time.Time `json:"timestamp"`
+	Model         string    `json:"model"`
+	TotalTokens   int32     `json:"totalTokens"`
+	InputTokens   int32     `json:"inputTokens"`
+	OutputTokens  int32     `json:"outputTokens"`
+	Duration      float64   `json:"duration"`
+	Candidates    int       `json:"candidates"`
+	FinishReasons []string  `json:"finishReasons,omitempty"`
+	ErrorClass    string    `json:"errorClass"`
+	CachedTokens  int32     `json:"cachedTokens,omitempty"`
+}
+
+// UsagePrice represents price (e.g. in USD) per one million tokens for an AI model
+type UsagePrice struct {
+	Input  float64 `yaml:"Input"`
+	Output float64 `yaml:"Output"`
+}
+
+// UsageSummary represents aggregated usage records
+type UsageSummary struct {
+	Requests     int
+	Errors       int
+	InputTokens  int64
+	OutputTokens int64
+	TotalTokens  int64
+	Cost         float64
+	Priced       bool
+}
+
+/*
+appendUsageRecord appends record for single request to AI model (each retry, fallback attempt and function call round)
+to usage ledger.
+*/
+func appendUsageRecord(model string, start time.Time, resp *genai.GenerateContentResponse, err error) {
+	finish := time.Now()
+	record := UsageRecord{
+		Timestamp:  finish,
+		Model:      strings.TrimPrefix(model, "models/"),
+		Duration:   finish.Sub(start).Seconds(),
+		ErrorClass: classifyError(err),
+	}
+	if err == nil && resp != nil {
+		record.Candidates = len(resp.Candidates)
+		for _, candidate := range resp.Candidates {
+			record.FinishReasons = append(record.FinishReasons, candidate.FinishReason.String())
+		}
+		if resp.UsageMetadata != nil {
+			record.TotalTokens = resp.UsageMetadata.TotalTokenCount
+			record.InputTokens = resp.UsageMetadata.PromptTokenCount
+			record.OutputTokens = resp.UsageMetadata.CandidatesTokenCount
+			record.CachedTokens = resp.UsageMetadata.CachedContentTokenCount
+		}
+	}
+
+	data, err := json.Marshal(record)
+	if err != nil {
+		fmt.Printf("error [%v] at json.Marshal()\n", err)
+		return
+	}
+	ledger, err := os.OpenFile(progConfig.UsageLedgerFile, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		fmt.Printf("error [%v] at os.OpenFile()\n", err)
+		return
+	}
+	defer ledger.Close()
+	fmt.Fprintf(ledger, "%s\n", data)
+}
+
+/*
+readUsageLedger reads all records from usage ledger.
+*/
+func readUsageLedger(filename string) ([]UsageRecord, error) {
+	records := []UsageRecord{}
+
+	file, err := os.Open(filename)
+	if err != nil {
+		return records, err
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+		var record UsageRecord
+		err = json.Unmarshal([]byte(line), &record)
+		if err != nil {
+			fmt.Printf("error [%v] parsing usage record [%s]\n", err, line)
+			continue
+		}
+		records = append(records, record)
+	}
+
+	return records, scanner.Err()
+}
+
+/*
+showUsageReport aggregates usage ledger by day, week and model and prints report.
+*/
+func showUsageReport() {
+	records, err := readUsageLedger(progConfig.UsageLedgerFile)
+	if err != nil {
+		fmt.Printf("error [%v] reading usage ledger\n", err)
+		return
+	}
+
+	byDay := map[string]*UsageSummary{}
+	byWeek := map[string]*UsageSummary{}
+	byModel := map[string]*UsageSummary{}
+	total := &UsageSummary{}
+	for _, record := range records {
+		local := record.Timestamp.Local()
+		year, week := local.ISOWeek()
+		addUsageRecord(byDay, local.Format("2006-01-02"), record)
+		addUsageRecord(byWeek, fmt.Sprintf("%d-W%02d", year, week), record)
+		addUsageRecord(byModel, record.Model, record)
+		total.add(record)
+	}
+
+	fmt.Printf("\nUsage report (%s, %d %s):\n", progConfig.UsageLedgerFile, len(records), pluralize(len(records), "request"))
+	printUsageSummaries("Day", byDay)
+	printUsageSummaries("Week", byWeek)
+	printUsageSummaries("Model", byModel)
+	printUsageSummaries("Total", map[string]*UsageSummary{"all": total})
+	if len(progConfig.UsagePrices) > 0 {
+		fmt.Printf("\nCost is estimated from 'UsagePrices' (price per one million tokens), '-' = no price defined.\n")
+	}
+	fmt.Printf("\n")
+}
+
+/*
+addUsageRecord adds usage record to named summary.
+*/
+func addUsageRecord(summaries map[string]*UsageSummary, key string, record UsageRecord) {
+	summary, ok := summaries[key]
+	if !ok {
+		summary = &UsageSummary{}
+		summaries[key] = summary
+	}
+	summary.add(record)
+}
+
+/*
+add adds usage record to summary (incl. estimated cost).
+*/
+func (s *UsageSummary) add(record UsageRecord) {
+	s.Requests++
+	if record.ErrorClass != "none" {
+		s.Errors++
+	}
+	s.InputTokens += int64(record.InputTokens)
+	s.OutputTokens += int64(record.OutputTokens)
+	s.TotalTokens += int64(record.TotalTokens)
+
+	price, ok := progConfig.UsagePrices[record.Model]
+	if ok {
+		s.Cost += float64(record.InputTokens)/1000000.0*price.Input + float64(record.OutputTokens)/1000000.0*price.Output
+		s.Priced = true
+	}
+}
+
+/*
+printUsageSummaries prints usage summaries sorted by key.
+*/
+func printUsageSummaries(title string, summaries map[string]*UsageSummary) {
+	keys := []string{}
+	for key := range summaries {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	fmt.Printf("\n  %-24s  %8s  %6s  %12s  %12s  %12s  %10s\n", title, "Requests", "Errors", "In", "Out", "Total", "Cost")
+	for _, key := range keys {
+		summary := summaries[key]
+		cost := "-"
+		if summary.Priced {
+			cost = fmt.Sprintf("%.4f", summary.Cost)
+		}
+		fmt.Printf("  %-24s  %8d  %6d  %12d  %12d  %12d  %10s\n", key, summary.Requests, summary.Errors,
+			summary.InputTokens, summary.OutputTokens, summary.TotalTokens, cost)
+	}
+}
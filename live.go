@@ -0,0 +1,210 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"path"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/aquilax/truncate"
+)
+
+// LiveEntry represents one prompt/response pair shown in live view
+type LiveEntry struct {
+	ID      int    `json:"id"`
+	Title   string `json:"title"`
+	HTML    string `json:"html"`
+	History string `json:"history,omitempty"`
+	Done    bool   `json:"done"`
+}
+
+// LiveChunk represents a chunk of streamed (partial) response text
+type LiveChunk struct {
+	ID   int    `json:"id"`
+	Text string `json:"text"`
+}
+
+// LiveEvent represents a server-sent event
+type LiveEvent struct {
+	Name string
+	Data []byte
+}
+
+// LiveView is a live-updating browser page fed via server-sent events (SSE)
+type LiveView struct {
+	mu      sync.Mutex
+	entries []*LiveEntry
+	clients map[chan LiveEvent]bool
+}
+
+// live view (nil = live view disabled)
+var liveView *LiveView
+
+/*
+newLiveView creates new live view.
+*/
+func newLiveView() *LiveView {
+	return &LiveView{clients: map[chan LiveEvent]bool{}}
+}
+
+/*
+liveViewURL builds URL of live view page.
+*/
+func liveViewURL() string {
+	return fmt.Sprintf("http://localhost:%d/live", progConfig.InputLocalhostPort)
+}
+
+/*
+registerHandlers registers live view handlers (page, events, assets, history, response attachments) at default http mux.
+*/
+func (lv *LiveView) registerHandlers() {
+	http.HandleFunc("/live", lv.handlePage)
+	http.HandleFunc("/events", lv.handleEvents)
+	http.HandleFunc("/assets/live-view.js", func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", "text/javascript; charset=utf-8")
+		_, _ = w.Write(assetsLiveViewJs)
+	})
+	http.Handle("/assets/", http.StripPrefix("/assets/", http.FileServer(http.Dir("./assets"))))
+	if progConfig.HTMLHistory {
+		http.Handle("/history/", http.StripPrefix("/history/", http.FileServer(http.Dir(progConfig.HTMLHistoryDirectory))))
+	}
+	if progConfig.ResponseAttachments {
+		// saved inline data of responses (e.g. images) is linked relative to page (e.g. 'attachments/...png')
+		prefix := "/" + path.Clean(filepath.ToSlash(progConfig.ResponseAttachmentsDirectory)) + "/"
+		http.Handle(prefix, http.StripPrefix(prefix, http.FileServer(http.Dir(progConfig.ResponseAttachmentsDirectory))))
+	}
+}
+
+/*
+handlePage serves live view page.
+*/
+func (lv *LiveView) handlePage(w http.ResponseWriter, _ *http.Request) {
+	var page strings.Builder
+	page.WriteString(fmt.Sprintf(progConfig.HTMLHeader, progName+" (live view)"))
+	page.WriteString("<div id=\"live-status\"></div>\n")
+	page.WriteString("<nav id=\"live-entries\"></nav>\n")
+	page.WriteString("<main id=\"live-view\"></main>\n")
+	page.WriteString("<script src=\"assets/live-view.js\"></script>\n")
+	page.WriteString(progConfig.HTMLFooter)
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	_, _ = w.Write([]byte(page.String()))
+}
+
+/*
+handleEvents sends all existing and all future live view events to browser (server-sent events).
+*/
+func (lv *LiveView) handleEvents(w http.ResponseWriter, r *http.Request) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "streaming not supported", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+
+	// send existing entries and register client
+	client := make(chan LiveEvent, 256)
+	lv.mu.Lock()
+	for _, entry := range lv.entries {
+		data, _ := json.Marshal(entry)
+		fmt.Fprintf(w, "event: entry\ndata: %s\n\n", data)
+	}
+	lv.clients[client] = true
+	lv.mu.Unlock()
+	flusher.Flush()
+
+	defer func() {
+		lv.mu.Lock()
+		delete(lv.clients, client)
+		lv.mu.Unlock()
+	}()
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case event := <-client:
+			fmt.Fprintf(w, "event: %s\ndata: %s\n\n", event.Name, event.Data)
+			flusher.Flush()
+		}
+	}
+}
+
+/*
+broadcast sends event to all connected browsers (slow browsers miss events).
+*/
+func (lv *LiveView) broadcast(name string, value any) {
+	data, err := json.Marshal(value)
+	if err != nil {
+		fmt.Printf("error [%v] at json.Marshal()\n", err)
+		return
+	}
+	for client := range lv.clients {
+		select {
+		case client <- LiveEvent{Name: name, Data: data}:
+		default:
+		}
+	}
+}
+
+/*
+StartEntry adds new entry for given prompt to live view.
+*/
+func (lv *LiveView) StartEntry(prompt, html string) {
+	lv.mu.Lock()
+	defer lv.mu.Unlock()
+
+	title := strings.Join(strings.Fields(prompt), " ")
+	title = truncate.Truncate(title, 80, "...", truncate.PositionEnd)
+	entry := &LiveEntry{ID: len(lv.entries) + 1, Title: title, HTML: html}
+	lv.entries = append(lv.entries, entry)
+	lv.broadcast("entry", entry)
+}
+
+/*
+AppendChunk appends streamed (partial) response text to current entry of live view.
+*/
+func (lv *LiveView) AppendChunk(text string) {
+	lv.mu.Lock()
+	defer lv.mu.Unlock()
+
+	if len(lv.entries) == 0 {
+		return
+	}
+	lv.broadcast("chunk", LiveChunk{ID: lv.entries[len(lv.entries)-1].ID, Text: text})
+}
+
+/*
+FinishEntry replaces content of current entry of live view with complete prompt/response pair.
+*/
+func (lv *LiveView) FinishEntry(html, historyFile string) {
+	lv.mu.Lock()
+	defer lv.mu.Unlock()
+
+	if len(lv.entries) == 0 {
+		return
+	}
+	entry := lv.entries[len(lv.entries)-1]
+	entry.HTML = html
+	entry.Done = true
+	if historyFile != "" {
+		entry.History = "history/" + url.PathEscape(historyFile)
+	}
+	lv.broadcast("entry", entry)
+}
+
+/*
+Status shows status message (e.g. rate limit wait) in live view (empty message = no status).
+*/
+func (lv *LiveView) Status(message string) {
+	lv.mu.Lock()
+	defer lv.mu.Unlock()
+
+	lv.broadcast("status", message)
+}
@@ -48,7 +48,6 @@ import (
 	"github.com/yuin/goldmark"
 	"github.com/yuin/goldmark/extension"
 	"golang.org/x/term"
-	"google.golang.org/api/option"
 )
 
 // general program info
@@ -66,6 +65,9 @@ var (
 	finishProcessing time.Time
 )
 
+// number of attempts needed to generate content (retry of transient errors)
+var generationAttempts int
+
 // markdown to html parser
 var markdownParser goldmark.Markdown
 
@@ -106,11 +108,19 @@ func main() {
 	topk := flag.Int("topk", -1, "maximum number of tokens to consider when sampling (overwrites YAML config)")
 	maxtokens := flag.Int("maxtokens", -1, "max output tokens (useful to force short content, overwrites YAML config)")
 	dryrun := flag.Bool("dryrun", false, "only print list of files given via command line")
-	uploads := flag.String("uploads", "", "name of list with files to upload to AI (one file per line)")
+	tokens := flag.Bool("tokens", false, "with -dryrun: count tokens of each file (file content is sent to Gemini)")
+	uploads := flag.String("uploads", "", "name of list with files, directories or globs to upload to AI (one entry per line)")
 	dir, _ := filepath.Split(os.Args[0])
 	defaultConfigFile := dir + progName + ".yaml"
 	config := flag.String("config", defaultConfigFile, "name of YAML config file")
 	models := flag.Bool("models", false, "show all AI Gemini models and terminate")
+	stream := flag.Bool("stream", false, "enables streaming mode, response text is shown as it arrives (overwrites YAML config)")
+	chat := flag.Bool("chat", false, "enables chat mode, prompts keep conversation context (overwrites YAML config)")
+	session := flag.String("session", "", "name of chat session to resume or create (enables chat mode)")
+	sessions := flag.Bool("sessions", false, "show all saved chat sessions and terminate")
+	usage := flag.Bool("usage", false, "show usage report (aggregated usage ledger) and terminate")
+	files := flag.String("files", "", "manage remote files: list, show name, delete name|pattern, purge (and terminate)")
+	extract := flag.Bool("extract", false, "extract fenced code blocks of responses from markdown files given via command line and terminate")
 
 	flag.Usage = printUsage
 	flag.Parse()
@@ -137,38 +147,56 @@ func main() {
 		os.Exit(1)
 	}
 
+	// create markdown parser
+	markdownParser = goldmark.New(goldmark.WithExtensions(extension.GFM))
+
 	if *models {
 		showAvailableGeminiModels(terminalWidth)
 		os.Exit(1)
 	}
 
-	var uploadFiles []string
+	if *sessions {
+		listChatSessions()
+		os.Exit(1)
+	}
+
+	if *usage {
+		showUsageReport()
+		os.Exit(1)
+	}
+
+	if *files != "" {
+		manageRemoteFiles(*files, flag.Args())
+		os.Exit(1)
+	}
+
+	if *extract {
+		extractCodeBlocksFromFiles(flag.Args())
+		os.Exit(1)
+	}
+
+	if *session != "" && !validChatSessionName.MatchString(*session) {
+		fmt.Printf("error: invalid chat session name [%s] (allowed characters: a-z, A-Z, 0-9, '.', '_', '-')\n", *session)
+		os.Exit(1)
+	}
+
+	var uploadEntries []string
 	if *uploads != "" {
-		uploadFiles, err = slurpFile(*uploads)
+		uploadEntries, err = readUploadList(*uploads)
 		if err != nil {
 			fmt.Printf("error [%v] reading list of files to upload to AI\n", err)
 		}
 	}
-	commandlineFiles := flag.Args()
-	allFiles := uploadFiles
-	allFiles = append(allFiles, commandlineFiles...)
+	commandlineEntries := flag.Args()
+	allEntries := uploadEntries
+	allEntries = append(allEntries, commandlineEntries...)
+	allFiles, skippedFiles := resolveUploadFiles(allEntries)
 
 	if *dryrun {
-		fmt.Printf("\nFiles given via command line:\n")
-		for _, file := range allFiles {
-			mimeType, err := getMimeType(file)
-			info := "ok"
-			if err != nil {
-				info = "error"
-			}
-			if mimeType == "application/octet-stream" {
-				info = "warn"
-			}
-			if err == nil {
-				fmt.Printf("  %-5s  %-32.32s  %s\n", info, mimeType, file)
-			} else {
-				fmt.Printf("  %-5s  %s\n", info, err)
-			}
+		// offline listing first, token counting (transmits file content) only on request
+		convertedFiles := printResolvedFiles(allFiles, skippedFiles)
+		if *tokens {
+			printFileTokens(allFiles, convertedFiles)
 		}
 		fmt.Printf("\n")
 		return
@@ -177,56 +205,55 @@ func main() {
 	// show configuration
 	showConfiguration()
 
-	// initialize this program
-	initializeProgram()
-
-	// overwrite YAML config values with cli parameters
+	// overwrite YAML config values with cli parameters (before initialization, e.g. chat session directory)
 	if *candidates > 0 {
 		progConfig.GeminiCandidateCount = int32(*candidates)
+		commandLineGenerationConfig.SetCandidateCount(progConfig.GeminiCandidateCount)
 	}
 	if *temperature > -1.0 {
 		progConfig.GeminiTemperature = float32(*temperature)
+		commandLineGenerationConfig.SetTemperature(progConfig.GeminiTemperature)
 	}
 	if *topp > -1.0 {
 		progConfig.GeminiTopP = float32(*topp)
+		commandLineGenerationConfig.SetTopP(progConfig.GeminiTopP)
 	}
 	if *topk > -1 {
 		progConfig.GeminiTopK = int32(*topk)
+		commandLineGenerationConfig.SetTopK(progConfig.GeminiTopK)
 	}
 	if *maxtokens > 0 {
 		progConfig.GeminiMaxOutputTokens = int32(*maxtokens)
+		commandLineGenerationConfig.SetMaxOutputTokens(progConfig.GeminiMaxOutputTokens)
+	}
+	if *stream {
+		progConfig.GeminiStreamResponse = true
+	}
+	if *chat || *session != "" {
+		progConfig.ChatMode = true
 	}
 
-	// create markdown parser
-	markdownParser = goldmark.New(goldmark.WithExtensions(extension.GFM))
+	// initialize this program
+	initializeProgram()
 
 	// create AI client
-	var client *genai.Client
 	ctx := context.Background()
-	if progConfig.GeneralInternetProxy != "" {
-		// indirect internet connection: client -> proxy -> internet
-		httpClient := &http.Client{Transport: &ProxyRoundTripper{
-			APIKey:   progConfig.GeminiAPIKey,
-			ProxyURL: progConfig.GeneralInternetProxy,
-		}}
-		// option.WithAPIKey() shouldn't be necessary because the key is set in ProxyRoundTripper
-		// but without the option, NewClient() attempts to authenticate via Google Cloud SDK (ADC)
-		client, err = genai.NewClient(ctx, option.WithAPIKey(progConfig.GeminiAPIKey), option.WithHTTPClient(httpClient))
-	} else {
-		// direct internet connection: client -> internet
-		client, err = genai.NewClient(ctx, option.WithAPIKey(progConfig.GeminiAPIKey))
-	}
+	client, err := createClient(ctx)
 	if err != nil {
 		fmt.Printf("error [%v] creating AI client\n", err)
 		os.Exit(1)
 	}
 
-	// upload all files given from command line
-	uploadedFiles, err = uploadFilesToGemini(ctx, client, allFiles)
+	// upload all files given from command line (reuse remote files of previous program runs)
+	if progConfig.UploadCache {
+		uploadCache = loadUploadCache()
+	}
+	uploadedFiles, inlinedFiles, err = prepareFiles(ctx, client, allFiles)
 	if err != nil {
 		fmt.Printf("error [%v] uploading files\n", err)
 		return
 	}
+	watchFiles(allFiles)
 
 	// define Gemini AI model
 	geminiModel := client.GenerativeModel(progConfig.GeminiAiModel)
@@ -257,9 +284,63 @@ func main() {
 	if progConfig.GeminiSystemInstruction != "" {
 		geminiModel.SystemInstruction = genai.NewUserContent(genai.Text(progConfig.GeminiSystemInstruction))
 	}
+	geminiModel.SafetySettings, err = buildSafetySettings()
+	if err != nil {
+		fmt.Printf("error [%v] building safety settings\n", err)
+		return
+	}
+
+	// count tokens of uploaded files (-1 = unknown)
+	filesTokens := int32(-1)
+	if progConfig.GeminiTokenLimitPolicy != "none" {
+		filesTokens, err = countUploadedFilesTokens(ctx, geminiModel)
+		if err != nil {
+			fmt.Printf("error [%v] counting tokens of uploaded files\n", err)
+			filesTokens = -1
+		}
+	}
+
+	// declare functions which can be called by AI model
+	if len(progConfig.FunctionTools) > 0 {
+		functionTool, err := buildFunctionTool()
+		if err != nil {
+			fmt.Printf("error [%v] building function declarations\n", err)
+			return
+		}
+		geminiModel.Tools = append(geminiModel.Tools, functionTool)
+	}
+
+	// enable code execution by AI model (Python, executed by Gemini)
+	if progConfig.CodeExecution {
+		geminiModel.Tools = append(geminiModel.Tools, &genai.Tool{CodeExecution: &genai.CodeExecution{}})
+	}
+
+	// put attached files and system instruction into context cache
+	if progConfig.ContextCache {
+		createContextCache(ctx, client, geminiModel)
+	}
 
 	// print AI model information
-	printAIModelInfo(geminiModel, modelInfo, terminalWidth)
+	printAIModelInfo(geminiModel, modelInfo, terminalWidth, filesTokens)
+
+	// start new chat (conversation) or resume saved chat session
+	if progConfig.ChatMode {
+		if *session != "" && chatSessionExists(*session) {
+			err = resumeChatSession(ctx, client, geminiModel, *session)
+			if err != nil {
+				fmt.Printf("error [%v] resuming chat session\n", err)
+				return
+			}
+			printChatInfo()
+		} else {
+			startNewChat(geminiModel)
+			chatSessionName = *session
+			chatSessionCreated = time.Now()
+			if chatSessionName == "" {
+				chatSessionName = buildChatSessionName(chatSessionCreated)
+			}
+		}
+	}
 
 	// define prompt channel
 	promptChannel := make(chan string)
@@ -273,45 +354,140 @@ func main() {
 	fmt.Printf("  Press CTRL-C to terminate this program.\n\n")
 
 	// start graceful shutdown handler
-	go handleShutdown(ctx, shutdownTrigger, client, uploadedFiles)
+	go handleShutdown(ctx, shutdownTrigger, client)
 
 	// start input readers
 	inputPossibilities := startInputReaders(promptChannel, progConfig)
 
+	// keep attached files in sync with local changes and remote expiration
+	if progConfig.UploadWatchFiles {
+		go startFileWatcher()
+	}
+
+	// open live view page once (instead of one page per response)
+	if liveView != nil && progConfig.HTMLOutput {
+		err = runCommand(fmt.Sprintf(progConfig.HTMLOutputApplication, liveViewURL()))
+		if err != nil {
+			fmt.Printf("error [%v] at runCommand()\n", err)
+		}
+	}
+
 	// main loop: 'Prompt Google Gemini AI'
 	for {
 		fmt.Printf("Waiting for input from %s ...\n", strings.Join(inputPossibilities, ", "))
 
-		// read prompt from channel
-		prompt := <-promptChannel
+		// read prompt from channel (or sync attached files signaled by file watcher)
+		var prompt string
+		select {
+		case prompt = <-promptChannel:
+		case <-syncChannel:
+			syncAttachedFiles(ctx, client, geminiModel)
+			continue
+		}
 		prompt = strings.TrimSpace(prompt)
 
+		// handle program command (e.g. '/new')
+		if isCommand(prompt) {
+			handleCommand(ctx, client, geminiModel, prompt)
+			continue
+		}
+
 		now := time.Now()
 		if progConfig.NotifyPrompt {
 			_ = runCommand(progConfig.NotifyPromptApplication)
 		}
 		fmt.Printf("%02d:%02d:%02d: Processing prompt ...\n", now.Hour(), now.Minute(), now.Second())
-		processPrompt(prompt)
 
-		// build prompt with all parts (files and text)
+		// attached files changed since last check are synced before prompt is sent
+		if progConfig.UploadWatchFiles {
+			syncAttachedFiles(ctx, client, geminiModel)
+		}
+
+		// resolve directives (data referenced by this prompt only, e.g. '@file: docs/spec.pdf')
+		var promptAttachments []PromptAttachment
+		var directives []PromptDirective
+		if progConfig.PromptDirectives {
+			prompt, directives = parsePromptDirectives(prompt)
+			promptAttachments = resolvePromptDirectives(ctx, client, directives)
+		}
+
+		// structured output: JSON response with schema of configuration or '@schema:' directive
+		applyResponseSchema(geminiModel, promptResponseSchema(directives))
+
+		// build prompt with all parts (files and text), in chat mode each file is sent only once
+		// attached files in context cache are not sent (cache is extended while in use)
 		promptParts := []genai.Part{}
+		extendContextCache(ctx, client, geminiModel)
 		for _, uploadedFile := range uploadedFiles {
+			if contextCache != nil || (chatSession != nil && isFileInChatHistory(uploadedFile.URI)) {
+				continue
+			}
 			promptParts = append(promptParts, genai.FileData{URI: uploadedFile.URI})
 		}
-		promptParts = append(promptParts, genai.Text(prompt))
+		for _, inlinedFile := range inlinedFiles {
+			if contextCache != nil || (chatSession != nil && isInlineFileInChatHistory(inlinedFile)) {
+				continue
+			}
+			promptParts = append(promptParts, inlinedFile.Parts...)
+		}
+		for _, promptAttachment := range promptAttachments {
+			promptParts = append(promptParts, promptAttachment.Parts...)
+		}
+		if prompt != "" {
+			promptParts = append(promptParts, genai.Text(prompt))
+		}
+		if len(promptParts) == 0 {
+			fmt.Printf("error: prompt empty (nothing to send)\n")
+			continue
+		}
+
+		// pre-flight token counting (checks input token limit, estimate for client-side TPM limits)
+		promptTokens := int32(0)
+		if progConfig.GeminiTokenLimitPolicy != "none" || hasTokenRateLimit() {
+			promptTokens, err = countPromptTokens(ctx, geminiModel, promptParts)
+			if err != nil {
+				fmt.Printf("error [%v] counting prompt tokens\n", err)
+			} else if progConfig.GeminiTokenLimitPolicy != "none" && !checkTokenLimit(promptTokens) {
+				cleanupPromptAttachments(ctx, client, promptAttachments)
+				continue
+			}
+		}
+
+		promptMarkdown := processPrompt(prompt, promptAttachments)
+		if liveView != nil {
+			liveView.StartEntry(prompt, renderMarkdown2HTML(promptMarkdown))
+		}
 
 		// generate content
 		startProcessing = time.Now()
 		var resp *genai.GenerateContentResponse
-		resp, err = geminiModel.GenerateContent(ctx, promptParts...)
+		resp, err = generateWithFallback(ctx, client, geminiModel, promptParts, promptTokens)
 		if err != nil {
 			fmt.Printf("error [%v] generating content\n", err)
 		}
 		finishProcessing = time.Now()
+		cleanupPromptAttachments(ctx, client, promptAttachments)
 
 		now = finishProcessing
 		fmt.Printf("%02d:%02d:%02d: Processing response ...\n", now.Hour(), now.Minute(), now.Second())
-		processResponse(resp, err)
+		if err == nil {
+			saveResponseBlobs(now, prompt, workingDirectory, resp)
+		}
+		responseMarkdown := processResponse(resp, err)
+
+		// write code blocks of response to files
+		if progConfig.ExtractCodeBlocks {
+			extractResponseCodeBlocks(now, prompt, responseMarkdown)
+		}
+
+		// add prompt/response pair to chat transcript and save chat session
+		if chatSession != nil && countChatTurns() > len(chatTranscript) {
+			chatTranscript = append(chatTranscript, promptMarkdown+responseMarkdown)
+			err = saveChatSession(geminiModel)
+			if err != nil {
+				fmt.Printf("error [%v] saving chat session\n", err)
+			}
+		}
 
 		// trigger response notification
 		if progConfig.NotifyResponse {
@@ -330,6 +506,9 @@ func main() {
 			copyFile(progConfig.AnsiPromptResponseFile, ansiDestinationPathFile)
 		}
 
+		// save JSON response (structured output) to history
+		saveJSONHistory(now, prompt, workingDirectory, resp)
+
 		// markdown prompt and response file: nothing to do
 		commandLine := fmt.Sprintf(progConfig.MarkdownOutputApplication, progConfig.MarkdownPromptResponseFile)
 
@@ -354,15 +533,21 @@ func main() {
 		_ = buildHTMLPage(prompt, progConfig.HTMLPromptResponseFile, progConfig.HTMLPromptResponseFile)
 
 		// copy html file to history
+		htmlDestinationFile := ""
 		if progConfig.HTMLHistory {
-			htmlDestinationFile := buildDestinationFilename(now, prompt, progConfig.HistoryFilenameExtensionHTML)
+			htmlDestinationFile = buildDestinationFilename(now, prompt, progConfig.HistoryFilenameExtensionHTML)
 			htmlDestinationPathFile := filepath.Join(workingDirectory, progConfig.HTMLHistoryDirectory, htmlDestinationFile)
 			copyFile(progConfig.HTMLPromptResponseFile, htmlDestinationPathFile)
 			commandLine = fmt.Sprintf(progConfig.HTMLOutputApplication, "\""+htmlDestinationPathFile+"\"")
 		}
 
-		// open html page in application
-		if progConfig.HTMLOutput {
+		// update live view with complete prompt/response pair
+		if liveView != nil {
+			liveView.FinishEntry(renderMarkdown2HTML(promptMarkdown+responseMarkdown), htmlDestinationFile)
+		}
+
+		// open html page in application (live view updates itself)
+		if progConfig.HTMLOutput && liveView == nil {
 			err := runCommand(commandLine)
 			if err != nil {
 				fmt.Printf("error [%v] at runCommand()\n", err)
@@ -374,7 +559,7 @@ func main() {
 /*
 printAIModelInfo prints AI model information to the console.
 */
-func printAIModelInfo(geminiModel *genai.GenerativeModel, modelInfo *genai.ModelInfo, terminalWidth int) {
+func printAIModelInfo(geminiModel *genai.GenerativeModel, modelInfo *genai.ModelInfo, terminalWidth int, filesTokens int32) {
 	// calculate words from tokens
 	inputTokenLimitWordsLower := float64(modelInfo.InputTokenLimit) / 100.0 * 60.0
 	inputTokenLimitWordsLower = math.Floor(inputTokenLimitWordsLower/100.0) * 100.0
@@ -392,6 +577,9 @@ func printAIModelInfo(geminiModel *genai.GenerativeModel, modelInfo *genai.Model
 	fmt.Printf("  DisplayName       : %v\n", modelInfo.DisplayName)
 	fmt.Printf("  Description       : %v\n", wrapString(modelInfo.Description, terminalWidth, 22))
 	fmt.Printf("  InputTokenLimit   : %v (approx. %.0f-%.0f english words)\n", modelInfo.InputTokenLimit, inputTokenLimitWordsLower, inputTokenLimitWordsUpper)
+	if filesTokens > -1 {
+		fmt.Printf("  UploadedFiles     : %v tokens (%.1f%% of InputTokenLimit)\n", filesTokens, tokenLimitUsage(filesTokens))
+	}
 	fmt.Printf("  OutputTokenLimit  : %v (approx. %.0f-%.0f english words)\n", modelInfo.OutputTokenLimit, outputTokenLimitWordsLower, outputTokenLimitWordsUpper)
 	fmt.Printf("  Supported Methods : %v\n", strings.Join(modelInfo.SupportedGenerationMethods, ", "))
 	fmt.Printf("  Temperature       : %v\n", modelInfo.Temperature)
@@ -417,6 +605,36 @@ func printAIModelInfo(geminiModel *genai.GenerativeModel, modelInfo *genai.Model
 	if geminiModel.GenerationConfig.TopK != nil {
 		fmt.Printf("  TopK              : %v\n", *geminiModel.GenerationConfig.TopK)
 	}
+	if progConfig.GeminiStreamResponse {
+		fmt.Printf("  StreamResponse    : yes\n")
+	}
+	if progConfig.ChatMode {
+		fmt.Printf("  ChatMode          : yes (one candidate per response)\n")
+	}
+	if len(progConfig.GeminiFallbackModels) > 0 {
+		fmt.Printf("  FallbackModels    : %v (on %v)\n", strings.Join(progConfig.GeminiFallbackModels, ", "),
+			strings.Join(progConfig.GeminiFallbackErrorClasses, ", "))
+	}
+	for _, function := range progConfig.FunctionTools {
+		confirm := ""
+		if function.Confirm {
+			confirm = ", confirm"
+		}
+		fmt.Printf("  Function          : %v (%v%s)\n", function.Name, truncate.Truncate(function.Description, 64, "...", truncate.PositionEnd), confirm)
+	}
+	if progConfig.CodeExecution {
+		fmt.Printf("  CodeExecution     : yes\n")
+	}
+	if len(geminiModel.SafetySettings) > 0 {
+		fmt.Printf("  SafetySettings    : %v\n", formatSafetySettings(geminiModel.SafetySettings))
+	}
+	if defaultResponseSchema != nil {
+		fmt.Printf("  ResponseSchema    : %v (JSON output)\n", defaultResponseSchema.Filename)
+	}
+	if contextCache != nil {
+		fmt.Printf("  ContextCache      : %v (TTL %d min, expires %s)\n", contextCache.Name, progConfig.ContextCacheTTL,
+			contextCache.Expiration.ExpireTime.Local().Format("2006-01-02 15:04"))
+	}
 	if progConfig.GeminiSystemInstruction != "" {
 		truncatedSystemInstruction := truncate.Truncate(progConfig.GeminiSystemInstruction, 96, "...", truncate.PositionMiddle)
 		fmt.Printf("  SystemInstruction : %v\n", truncatedSystemInstruction)
@@ -426,16 +644,38 @@ func printAIModelInfo(geminiModel *genai.GenerativeModel, modelInfo *genai.Model
 /*
 handleShutdown handles program termination signals.
 */
-func handleShutdown(ctx context.Context, shutdownTrigger chan os.Signal, client *genai.Client, uploadedFiles []*genai.File) {
+func handleShutdown(ctx context.Context, shutdownTrigger chan os.Signal, client *genai.Client) {
 	<-shutdownTrigger
 	fmt.Printf("\nShutdown signal received. Exiting gracefully ...\n")
 
-	// cleanup/delete all uploaded files before program termination
-	for _, uploadedFile := range uploadedFiles {
-		err := client.DeleteFile(ctx, uploadedFile.Name)
-		fmt.Printf("deleting uploaded remote file [%v]\n", uploadedFile.DisplayName)
-		if err != nil {
-			fmt.Printf("error [%v] deleting uploaded file\n", err)
+	// extend or delete context cache
+	closeContextCache(ctx, client)
+
+	// cleanup/delete all uploaded files before program termination (unless kept for next program run)
+	uploadedFilesMu.Lock()
+	defer uploadedFilesMu.Unlock()
+	if progConfig.UploadKeepFiles {
+		fmt.Printf("keeping %d uploaded remote %s (upload cache)\n", len(uploadedFiles)+len(promptUploadedFiles),
+			pluralize(len(uploadedFiles)+len(promptUploadedFiles), "file"))
+	} else {
+		// attached files with same content share one remote file (upload cache)
+		deleted := map[string]bool{}
+		for _, uploadedFile := range append(uploadedFiles, promptUploadedFiles...) {
+			if deleted[uploadedFile.Name] {
+				continue
+			}
+			deleted[uploadedFile.Name] = true
+			err := client.DeleteFile(ctx, uploadedFile.Name)
+			fmt.Printf("deleting uploaded remote file [%v]\n", uploadedFile.DisplayName)
+			if err != nil {
+				fmt.Printf("error [%v] deleting uploaded file\n", err)
+			}
+			if uploadCache != nil {
+				uploadCache.remove(uploadedFile.Name)
+			}
+		}
+		if uploadCache != nil {
+			uploadCache.save()
 		}
 	}
 
@@ -478,8 +718,15 @@ func startInputReaders(promptChannel chan string, config ProgConfig) []string {
 	// input from localhost
 	if config.InputFromLocalhost {
 		addr := fmt.Sprintf("localhost:%d", config.InputLocalhostPort)
+		if config.HTMLLiveView {
+			liveView = newLiveView()
+			liveView.registerHandlers()
+		}
 		go func() {
 			http.HandleFunc("/", readPromptFromLocalhost(promptChannel))
+			http.HandleFunc("/attach", handleAttachmentCommand(promptChannel, "/attach"))
+			http.HandleFunc("/detach", handleAttachmentCommand(promptChannel, "/detach"))
+			http.HandleFunc("/attachments", handleAttachmentCommand(promptChannel, "/attachments"))
 			err := http.ListenAndServe(addr, nil)
 			if err != nil {
 				fmt.Printf("error [%v] starting internal webserver\n", err)
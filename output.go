@@ -1,6 +1,8 @@
 package main
 
 import (
+	"encoding/json"
+	"errors"
 	"fmt"
 	"os"
 	"strings"
@@ -22,9 +24,9 @@ func printPromptResponseToTerminal() {
 }
 
 /*
-processPrompt processes (user input) prompt.
+processPrompt processes (user input) prompt and data referenced by this prompt only and returns prompt as markdown.
 */
-func processPrompt(prompt string) {
+func processPrompt(prompt string, promptAttachments []PromptAttachment) string {
 	var promptString strings.Builder
 
 	// text part of prompt
@@ -45,7 +47,7 @@ func processPrompt(prompt string) {
 	}
 
 	// data part of prompt
-	if len(uploadedFiles) > 0 {
+	if len(uploadedFiles) > 0 || len(inlinedFiles) > 0 {
 		promptString.WriteString("**Data referenced by the Prompt:**\n")
 		promptString.WriteString("\n```plaintext\n")
 		for _, uploadedFile := range uploadedFiles {
@@ -55,50 +57,76 @@ func processPrompt(prompt string) {
 					float64(uploadedFile.SizeBytes)/1024.0, uploadedFile.MIMEType))
 			}
 		}
+		for _, inlinedFile := range inlinedFiles {
+			promptString.WriteString(fmt.Sprintf("%s (inline, %.1f KiB, %s)\n",
+				inlinedFile.Filename, float64(inlinedFile.Size)/1024.0, inlinedFile.MIMEType))
+		}
 		promptString.WriteString("```\n")
 		promptString.WriteString("\n***\n")
 	}
 
+	// data part of prompt (directives, e.g. '@file: docs/spec.pdf')
+	if len(promptAttachments) > 0 {
+		promptString.WriteString("**Data referenced by this Prompt only:**\n")
+		promptString.WriteString("\n```plaintext\n")
+		for _, promptAttachment := range promptAttachments {
+			promptString.WriteString(promptAttachment.Description + "\n")
+		}
+		promptString.WriteString("```\n")
+		promptString.WriteString("\n***\n")
+	}
+
+	// chat mode: prompt follows transcript of all previous prompt/response pairs
+	markdownData := promptString.String()
+	if chatSession != nil {
+		markdownData = strings.Join(chatTranscript, "") + markdownData
+	}
+
 	// write prompt to current markdown request/response file
-	err := os.WriteFile(progConfig.MarkdownPromptResponseFile, []byte(promptString.String()), 0666)
+	err := os.WriteFile(progConfig.MarkdownPromptResponseFile, []byte(markdownData), 0666)
 	if err != nil {
 		fmt.Printf("error [%v] at os.WriteFile()\n", err)
-		return
+		return promptString.String()
 	}
 
 	// render prompt as ansi
-	ansiData := promptString.String()
+	ansiData := markdownData
 	if progConfig.AnsiRendering {
-		ansiData = renderMarkdown2Ansi(promptString.String())
+		ansiData = renderMarkdown2Ansi(markdownData)
 	}
 
 	// write prompt to current ansi request/response file
 	err = os.WriteFile(progConfig.AnsiPromptResponseFile, []byte(ansiData), 0666)
 	if err != nil {
 		fmt.Printf("error [%v] at os.WriteFile()\n", err)
-		return
+		return promptString.String()
 	}
 
 	// render prompt as html
-	htmlData := promptString.String()
+	htmlData := markdownData
 	if progConfig.HTMLRendering {
-		htmlData = renderMarkdown2HTML(promptString.String())
+		htmlData = renderMarkdown2HTML(markdownData)
 	}
 
 	// write prompt to current html request/response file
 	err = os.WriteFile(progConfig.HTMLPromptResponseFile, []byte(htmlData), 0666)
 	if err != nil {
 		fmt.Printf("error [%v] at os.WriteFile()\n", err)
-		return
+		return promptString.String()
 	}
+
+	return promptString.String()
 }
 
 /*
-processResponse processes response from AI model.
+processResponse processes response from AI model and returns response as markdown.
 */
-func processResponse(resp *genai.GenerateContentResponse, err error) {
+func processResponse(resp *genai.GenerateContentResponse, err error) string {
 	var responseString strings.Builder
 
+	// function calls requested by AI model
+	responseString.WriteString(formatFunctionCalls())
+
 	if err == nil {
 		// print response candidate(s)
 		for i, candidate := range resp.Candidates {
@@ -111,7 +139,14 @@ func processResponse(resp *genai.GenerateContentResponse, err error) {
 				responseString.WriteString("No content available in this candidate.\n")
 				continue
 			}
+			if activeResponseSchema != nil {
+				// structured output: text parts form one JSON document
+				responseString.WriteString(formatStructuredResponse(candidateText(candidate)))
+			}
 			for j, part := range candidate.Content.Parts {
+				if _, ok := part.(genai.Text); ok && activeResponseSchema != nil {
+					continue
+				}
 				if len(candidate.Content.Parts) > 1 {
 					responseString.WriteString(fmt.Sprintf("\nPart #%d:\n", j+1))
 				}
@@ -120,6 +155,15 @@ func processResponse(resp *genai.GenerateContentResponse, err error) {
 					responseString.WriteString(fmt.Sprintf("%s\n", p))
 				case genai.FileData:
 					responseString.WriteString(fmt.Sprintf("File Data: URI=%s, MIME=%s\n", p.URI, p.MIMEType))
+				case genai.Blob:
+					responseString.WriteString(formatResponseBlob(p, i, j))
+				case genai.FunctionCall:
+					args, _ := json.Marshal(p.Args)
+					responseString.WriteString(fmt.Sprintf("Function Call: `%s(%s)`\n", p.Name, args))
+				case *genai.ExecutableCode:
+					responseString.WriteString(formatExecutableCode(p))
+				case *genai.CodeExecutionResult:
+					responseString.WriteString(formatCodeExecutionResult(p))
 				default:
 					responseString.WriteString(fmt.Sprintf("Unsupported part type: %T\n", part))
 				}
@@ -176,12 +220,22 @@ func processResponse(resp *genai.GenerateContentResponse, err error) {
 		// handle response error
 		responseString.WriteString("**Error Response from Gemini:**\n\n")
 		responseString.WriteString(err.Error())
+		var blockedError *genai.BlockedError
+		if errors.As(err, &blockedError) {
+			responseString.WriteString("\n\n")
+			responseString.WriteString(explainBlockedError(blockedError))
+		}
 		responseString.WriteString("\n***\n")
 	}
 
 	// print response metadata
 	responseString.WriteString("```plaintext\n")
-	responseString.WriteString(fmt.Sprintf("AI model   : %v (version %v)\n", strings.TrimPrefix(modelInfo.Name, "models/"), modelInfo.Version))
+	if responseModelInfo != modelInfo {
+		responseString.WriteString(fmt.Sprintf("AI model   : %v (version %v, fallback for %v)\n", strings.TrimPrefix(responseModelInfo.Name, "models/"),
+			responseModelInfo.Version, strings.TrimPrefix(modelInfo.Name, "models/")))
+	} else {
+		responseString.WriteString(fmt.Sprintf("AI model   : %v (version %v)\n", strings.TrimPrefix(modelInfo.Name, "models/"), modelInfo.Version))
+	}
 	responseString.WriteString(fmt.Sprintf("Generated  : %v\n", finishProcessing.Format(time.RFC850)))
 
 	duration := finishProcessing.Sub(startProcessing)
@@ -191,26 +245,48 @@ func processResponse(resp *genai.GenerateContentResponse, err error) {
 	} else {
 		responseString.WriteString(fmt.Sprintf("Processing : %.1f secs resulting in error\n", duration.Seconds()))
 	}
+	responseString.WriteString(fmt.Sprintf("Attempts   : %d\n", generationAttempts))
 
 	if err == nil {
 		if resp.UsageMetadata != nil {
 			responseString.WriteString(fmt.Sprintf("Tokens     : %v (in: %v, out: %v)\n",
 				resp.UsageMetadata.TotalTokenCount, resp.UsageMetadata.PromptTokenCount, resp.UsageMetadata.CandidatesTokenCount))
+			if resp.UsageMetadata.CachedContentTokenCount > 0 && resp.UsageMetadata.PromptTokenCount > 0 {
+				responseString.WriteString(fmt.Sprintf("Cached     : %v of %v input tokens from context cache (%.1f%%)\n",
+					resp.UsageMetadata.CachedContentTokenCount, resp.UsageMetadata.PromptTokenCount,
+					float64(resp.UsageMetadata.CachedContentTokenCount)/float64(resp.UsageMetadata.PromptTokenCount)*100.0))
+			}
 		}
-		/* needs revision, output never seen */
-		if resp.PromptFeedback != nil {
+		if resp.PromptFeedback != nil && resp.PromptFeedback.BlockReason != genai.BlockReasonUnspecified {
 			responseString.WriteString(fmt.Sprintf("Blocked    : %v\n", resp.PromptFeedback.BlockReason.String()))
 		}
 	}
+	if chatSession != nil {
+		responseString.WriteString(fmt.Sprintf("Chat       : %d %s\n", countChatTurns(), pluralize(countChatTurns(), "turn")))
+	}
 
 	responseString.WriteString("```\n")
+
+	// print safety ratings of prompt and candidate(s) (html: collapsible section)
+	if err == nil {
+		if resp.PromptFeedback != nil {
+			responseString.WriteString(formatSafetyRatings("Safety Ratings (Prompt)", resp.PromptFeedback.SafetyRatings))
+		}
+		for i, candidate := range resp.Candidates {
+			title := "Safety Ratings (Response)"
+			if len(resp.Candidates) > 1 {
+				title = fmt.Sprintf("Safety Ratings (Candidate #%d)", i+1)
+			}
+			responseString.WriteString(formatSafetyRatings(title, candidate.SafetyRatings))
+		}
+	}
 	responseString.WriteString("\n***\n")
 
 	// append response string to current markdown request/response file
 	currentFileMarkdown, err := os.OpenFile(progConfig.MarkdownPromptResponseFile, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
 	if err != nil {
 		fmt.Printf("error [%v] at os.OpenFile()\n", err)
-		return
+		return responseString.String()
 	}
 	defer currentFileMarkdown.Close()
 	fmt.Fprint(currentFileMarkdown, responseString.String())
@@ -225,7 +301,7 @@ func processResponse(resp *genai.GenerateContentResponse, err error) {
 	currentFileAnsi, err := os.OpenFile(progConfig.AnsiPromptResponseFile, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
 	if err != nil {
 		fmt.Printf("error [%v] at os.OpenFile()\n", err)
-		return
+		return responseString.String()
 	}
 	defer currentFileAnsi.Close()
 	fmt.Fprint(currentFileAnsi, ansiData)
@@ -240,8 +316,10 @@ func processResponse(resp *genai.GenerateContentResponse, err error) {
 	currentFileHTML, err := os.OpenFile(progConfig.HTMLPromptResponseFile, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
 	if err != nil {
 		fmt.Printf("error [%v] at os.OpenFile()\n", err)
-		return
+		return responseString.String()
 	}
 	defer currentFileHTML.Close()
 	fmt.Fprint(currentFileHTML, htmlData)
+
+	return responseString.String()
 }
@@ -0,0 +1,211 @@
+package main
+
+import (
+	"fmt"
+	"sync/atomic"
+	"time"
+
+	"github.com/google/generative-ai-go/genai"
+)
+
+// RateLimit represents client-side rate limits of an AI model (0 = no limit)
+type RateLimit struct {
+	RPM int `yaml:"RPM"` // requests per minute
+	RPD int `yaml:"RPD"` // requests per day
+	TPM int `yaml:"TPM"` // tokens per minute
+}
+
+// RateLimiter enforces rate limits of an AI model based on recent requests
+type RateLimiter struct {
+	limit    RateLimit
+	requests []RateLimitRequest // requests of current day
+}
+
+// RateLimitRequest represents a request counted by rate limiter
+type RateLimitRequest struct {
+	Timestamp time.Time
+	Tokens    int
+}
+
+// rate limiters per AI model
+var rateLimiters = map[string]*RateLimiter{}
+
+// number of prompts waiting to be processed (queued by input readers, excl. prompt in process)
+var pendingPrompts atomic.Int32
+
+/*
+getRateLimiter gets rate limiter for AI model (nil = no rate limits configured).
+*/
+func getRateLimiter(model string) *RateLimiter {
+	limit, ok := progConfig.RateLimits[model]
+	if !ok {
+		return nil
+	}
+	rateLimiter, ok := rateLimiters[model]
+	if !ok {
+		rateLimiter = &RateLimiter{limit: limit}
+		rateLimiter.loadFromLedger(model)
+		rateLimiters[model] = rateLimiter
+	}
+	return rateLimiter
+}
+
+/*
+hasTokenRateLimit checks if TPM limit is configured for primary or fallback AI model (requires token estimate of prompt).
+*/
+func hasTokenRateLimit() bool {
+	for _, model := range append([]string{progConfig.GeminiAiModel}, progConfig.GeminiFallbackModels...) {
+		if progConfig.RateLimits[model].TPM > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+/*
+loadFromLedger initializes rate limiter with today's requests from usage ledger (daily quota survives restart).
+*/
+func (rl *RateLimiter) loadFromLedger(model string) {
+	if !progConfig.UsageLedger || !fileExists(progConfig.UsageLedgerFile) {
+		return
+	}
+	records, err := readUsageLedger(progConfig.UsageLedgerFile)
+	if err != nil {
+		fmt.Printf("error [%v] reading usage ledger\n", err)
+		return
+	}
+	today := time.Now().Format("2006-01-02")
+	for _, record := range records {
+		if record.Model == model && record.Timestamp.Local().Format("2006-01-02") == today {
+			rl.requests = append(rl.requests, RateLimitRequest{Timestamp: record.Timestamp, Tokens: int(record.TotalTokens)})
+		}
+	}
+}
+
+/*
+Delay calculates time to wait before next request with given (estimated) tokens can be sent.
+Returns zero duration if request can be sent immediately and the name of the limit that applies.
+*/
+func (rl *RateLimiter) Delay(now time.Time, tokens int) (time.Duration, string) {
+	rl.removeExpired(now)
+
+	// requests per day: wait until next day
+	if rl.limit.RPD > 0 && len(rl.requests) >= rl.limit.RPD {
+		year, month, day := now.Date()
+		midnight := time.Date(year, month, day+1, 0, 0, 0, 0, now.Location())
+		return midnight.Sub(now), fmt.Sprintf("RPD %d", rl.limit.RPD)
+	}
+
+	// requests and tokens of last minute
+	minuteAgo := now.Add(-time.Minute)
+	lastMinute := []RateLimitRequest{}
+	for _, request := range rl.requests {
+		if request.Timestamp.After(minuteAgo) {
+			lastMinute = append(lastMinute, request)
+		}
+	}
+
+	// requests per minute: wait until oldest request of last minute expires
+	if rl.limit.RPM > 0 && len(lastMinute) >= rl.limit.RPM {
+		oldest := lastMinute[len(lastMinute)-rl.limit.RPM]
+		return oldest.Timestamp.Add(time.Minute).Sub(now), fmt.Sprintf("RPM %d", rl.limit.RPM)
+	}
+
+	// tokens per minute: wait until enough tokens of last minute expire
+	if rl.limit.TPM > 0 {
+		usedTokens := 0
+		for _, request := range lastMinute {
+			usedTokens += request.Tokens
+		}
+		var delay time.Duration
+		for _, request := range lastMinute {
+			if usedTokens+tokens <= rl.limit.TPM {
+				break
+			}
+			usedTokens -= request.Tokens
+			delay = request.Timestamp.Add(time.Minute).Sub(now)
+		}
+		if delay > 0 {
+			return delay, fmt.Sprintf("TPM %d", rl.limit.TPM)
+		}
+	}
+
+	return 0, ""
+}
+
+/*
+Record records request sent at given time with given tokens.
+*/
+func (rl *RateLimiter) Record(timestamp time.Time, tokens int) {
+	rl.requests = append(rl.requests, RateLimitRequest{Timestamp: timestamp, Tokens: tokens})
+}
+
+/*
+recordRateLimitRequest counts request sent to AI model (tokens of response if available, estimated tokens otherwise).
+*/
+func recordRateLimitRequest(model string, timestamp time.Time, tokens int, resp *genai.GenerateContentResponse, err error) {
+	rateLimiter := getRateLimiter(model)
+	if rateLimiter == nil {
+		return
+	}
+	if err == nil && resp != nil && resp.UsageMetadata != nil {
+		tokens = int(resp.UsageMetadata.TotalTokenCount)
+	}
+	rateLimiter.Record(timestamp, tokens)
+}
+
+/*
+removeExpired removes requests of previous days.
+*/
+func (rl *RateLimiter) removeExpired(now time.Time) {
+	today := now.Format("2006-01-02")
+	requests := []RateLimitRequest{}
+	for _, request := range rl.requests {
+		if request.Timestamp.Local().Format("2006-01-02") == today {
+			requests = append(requests, request)
+		}
+	}
+	rl.requests = requests
+}
+
+/*
+waitForRateLimit blocks until request can be sent without exceeding rate limits of AI model.
+Queue position and expected wait are shown in terminal and live view.
+*/
+func waitForRateLimit(model string, tokens int) {
+	rateLimiter := getRateLimiter(model)
+	if rateLimiter == nil {
+		return
+	}
+
+	for {
+		delay, limit := rateLimiter.Delay(time.Now(), tokens)
+		if delay <= 0 {
+			break
+		}
+		delay = delay.Round(time.Second) + time.Second
+		// prompt in process is first in queue, followed by prompts of all input channels
+		waiting := int(pendingPrompts.Load()) + 1
+		now := time.Now()
+		status := fmt.Sprintf("Rate limit [%s] reached, prompt waits %v (%d %s waiting incl. this one)",
+			limit, delay, waiting, pluralize(waiting, "prompt"))
+		fmt.Printf("%02d:%02d:%02d: %s ...\n", now.Hour(), now.Minute(), now.Second(), status)
+		if liveView != nil {
+			liveView.Status(status)
+		}
+		time.Sleep(delay)
+	}
+
+	if liveView != nil {
+		liveView.Status("")
+	}
+}
+
+/*
+submitPrompt sends prompt to main loop (counts prompts waiting in queue).
+*/
+func submitPrompt(promptChannel chan string, prompt string) {
+	pendingPrompts.Add(1)
+	defer pendingPrompts.Add(-1)
+	promptChannel <- prompt
+}
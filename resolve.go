@@ -0,0 +1,448 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// PathPattern represents a gitignore-style path pattern (e.g. '*.log', 'build/', '/vendor', 'src/**/*.go')
+type PathPattern struct {
+	regex   *regexp.Regexp
+	negate  bool   // '!pattern': re-include path
+	dirOnly bool   // 'pattern/': matches only directories
+	base    string // directory the pattern is relative to (absolute path)
+}
+
+// SkippedFile represents a file not selected for upload
+type SkippedFile struct {
+	Path   string
+	Reason string
+}
+
+// max number of bytes read for binary file detection
+const binaryDetectionSize = 8000
+
+/*
+readUploadList reads list of files, directories and globs to upload (empty lines and '#' comments are ignored).
+*/
+func readUploadList(filename string) ([]string, error) {
+	lines, err := slurpFile(filename)
+	if err != nil {
+		return nil, err
+	}
+
+	entries := []string{}
+	for _, line := range lines {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		entries = append(entries, line)
+	}
+	return entries, nil
+}
+
+/*
+resolveUploadFiles resolves files, directories and globs to list of files.
+Explicitly named files are always selected. Files found in directories or via globs are filtered
+by .gitignore files, include/exclude patterns, max file size and binary file detection.
+*/
+func resolveUploadFiles(entries []string) ([]string, []SkippedFile) {
+	files := []string{}
+	skipped := []SkippedFile{}
+	selected := map[string]bool{}
+	rejected := map[string]bool{}
+
+	addFile := func(filename string) {
+		if !selected[filename] {
+			selected[filename] = true
+			files = append(files, filename)
+		}
+	}
+	skipFile := func(filename, reason string) {
+		if !rejected[filename] {
+			rejected[filename] = true
+			skipped = append(skipped, SkippedFile{Path: filename, Reason: reason})
+		}
+	}
+
+	for _, entry := range entries {
+		if isGlobPattern(entry) {
+			pattern := path.Clean(filepath.ToSlash(entry))
+			regex, err := regexp.Compile("^" + globToRegexp(pattern) + "$")
+			if err != nil {
+				fmt.Printf("error [%v] compiling glob [%s]\n", err, entry)
+				continue
+			}
+			root := filepath.FromSlash(globRoot(pattern))
+			matched := false
+			walkUploadDirectory(root, func(filename string, _ fs.FileInfo) bool {
+				if regex.MatchString(filepath.ToSlash(filename)) {
+					matched = true
+					return true
+				}
+				return false
+			}, addFile, skipFile)
+			if !matched {
+				fmt.Printf("warning: glob [%s] doesn't match any file\n", entry)
+			}
+			continue
+		}
+
+		info, err := os.Stat(entry)
+		if err == nil && info.IsDir() {
+			walkUploadDirectory(entry, func(string, fs.FileInfo) bool { return true }, addFile, skipFile)
+			continue
+		}
+
+		// file (non-existing files are reported when used)
+		addFile(entry)
+	}
+
+	return files, skipped
+}
+
+/*
+walkUploadDirectory walks directory tree and selects files accepted by match function and upload filters.
+*/
+func walkUploadDirectory(root string, match func(string, fs.FileInfo) bool, addFile func(string), skipFile func(string, string)) {
+	root = filepath.Clean(root)
+	excludePatterns := compilePathPatterns(progConfig.UploadExcludePatterns, root)
+	includePatterns := compilePathPatterns(progConfig.UploadIncludePatterns, root)
+	gitignorePatterns := map[string][]*PathPattern{}
+	if progConfig.UploadRespectGitignore {
+		gitignorePatterns[root] = loadParentGitignores(root)
+	}
+
+	err := filepath.WalkDir(root, func(filename string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			fmt.Printf("error [%v] walking directory [%s]\n", err, filename)
+			return nil
+		}
+
+		// patterns of all .gitignore files from repository root to current directory
+		dir := filepath.Dir(filename)
+		if filename == root {
+			dir = root
+		}
+		ignorePatterns := gitignorePatterns[dir]
+
+		if entry.IsDir() {
+			if filename != root {
+				if entry.Name() == ".git" || matchPathPatterns(ignorePatterns, filename, true) ||
+					matchPathPatterns(excludePatterns, filename, true) {
+					return filepath.SkipDir
+				}
+			}
+			if progConfig.UploadRespectGitignore {
+				ignorePatterns = append(ignorePatterns[:len(ignorePatterns):len(ignorePatterns)], loadGitignore(filename)...)
+			}
+			gitignorePatterns[filename] = ignorePatterns
+			return nil
+		}
+
+		info, err := entry.Info()
+		if err != nil || !info.Mode().IsRegular() || !match(filename, info) {
+			return nil
+		}
+		switch {
+		case matchPathPatterns(ignorePatterns, filename, false):
+			skipFile(filename, "gitignore")
+		case matchPathPatterns(excludePatterns, filename, false):
+			skipFile(filename, "excluded")
+		case len(includePatterns) > 0 && !matchPathPatterns(includePatterns, filename, false):
+			skipFile(filename, "not included")
+		case progConfig.UploadMaxFileSize > 0 && info.Size() > progConfig.UploadMaxFileSize:
+			skipFile(filename, fmt.Sprintf("too large (%.1f KiB)", float64(info.Size())/1024.0))
+		case progConfig.UploadSkipBinaryFiles && isBinaryFile(filename):
+			skipFile(filename, "binary")
+		default:
+			addFile(filename)
+		}
+		return nil
+	})
+	if err != nil {
+		fmt.Printf("error [%v] walking directory [%s]\n", err, root)
+	}
+}
+
+/*
+loadGitignore loads patterns of .gitignore file in given directory (if any).
+*/
+func loadGitignore(dir string) []*PathPattern {
+	filename := filepath.Join(dir, ".gitignore")
+	if !fileExists(filename) {
+		return nil
+	}
+	lines, err := slurpFile(filename)
+	if err != nil {
+		fmt.Printf("error [%v] reading [%s]\n", err, filename)
+		return nil
+	}
+
+	patterns := []string{}
+	for _, line := range lines {
+		line = strings.TrimRight(line, " \t\r")
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		patterns = append(patterns, line)
+	}
+	return compilePathPatterns(patterns, dir)
+}
+
+/*
+loadParentGitignores loads patterns of .gitignore files in parent directories of root (up to root of git repository).
+*/
+func loadParentGitignores(root string) []*PathPattern {
+	dir, err := filepath.Abs(root)
+	if err != nil {
+		return nil
+	}
+
+	parents := []string{}
+	for {
+		// '.git' is a directory (or a file in worktrees and submodules)
+		if _, err := os.Stat(filepath.Join(dir, ".git")); err == nil {
+			break
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			// not within git repository
+			return nil
+		}
+		dir = parent
+		parents = append([]string{dir}, parents...)
+	}
+
+	patterns := []*PathPattern{}
+	for _, parent := range parents {
+		patterns = append(patterns, loadGitignore(parent)...)
+	}
+	return patterns
+}
+
+/*
+compilePathPatterns compiles gitignore-style patterns relative to given base directory.
+*/
+func compilePathPatterns(patterns []string, base string) []*PathPattern {
+	pathPatterns := []*PathPattern{}
+	absoluteBase, err := filepath.Abs(base)
+	if err != nil {
+		fmt.Printf("error [%v] getting absolute path of [%s]\n", err, base)
+		return pathPatterns
+	}
+	for _, pattern := range patterns {
+		pathPattern := &PathPattern{base: absoluteBase}
+		if strings.HasPrefix(pattern, "!") {
+			pathPattern.negate = true
+			pattern = pattern[1:]
+		}
+		if strings.HasSuffix(pattern, "/") {
+			pathPattern.dirOnly = true
+			pattern = strings.TrimRight(pattern, "/")
+		}
+		if pattern == "" {
+			continue
+		}
+
+		// pattern without slash matches at any level, otherwise relative to base
+		expression := globToRegexp(strings.TrimPrefix(pattern, "/"))
+		if !strings.Contains(pattern, "/") {
+			expression = "(.*/)?" + expression
+		}
+		regex, err := regexp.Compile("^" + expression + "$")
+		if err != nil {
+			fmt.Printf("error [%v] compiling pattern [%s]\n", err, pattern)
+			continue
+		}
+		pathPattern.regex = regex
+		pathPatterns = append(pathPatterns, pathPattern)
+	}
+	return pathPatterns
+}
+
+/*
+matchPathPatterns checks if path is matched by patterns (last matching pattern wins, negation re-includes).
+*/
+func matchPathPatterns(patterns []*PathPattern, filename string, isDir bool) bool {
+	matched := false
+	if len(patterns) == 0 {
+		return matched
+	}
+	filename, err := filepath.Abs(filename)
+	if err != nil {
+		return matched
+	}
+	for _, pattern := range patterns {
+		if pattern.dirOnly && !isDir {
+			continue
+		}
+		relative, err := filepath.Rel(pattern.base, filename)
+		if err != nil {
+			continue
+		}
+		if pattern.regex.MatchString(filepath.ToSlash(relative)) {
+			matched = !pattern.negate
+		}
+	}
+	return matched
+}
+
+/*
+isGlobPattern checks if path contains glob meta characters.
+*/
+func isGlobPattern(name string) bool {
+	return strings.ContainsAny(name, "*?[")
+}
+
+/*
+globRoot gets static directory part of glob (leading path components without meta characters).
+*/
+func globRoot(pattern string) string {
+	components := strings.Split(pattern, "/")
+	root := []string{}
+	for _, component := range components[:len(components)-1] {
+		if isGlobPattern(component) {
+			break
+		}
+		root = append(root, component)
+	}
+	if len(root) == 0 {
+		return "."
+	}
+	if len(root) == 1 && root[0] == "" {
+		return "/"
+	}
+	return strings.Join(root, "/")
+}
+
+/*
+globToRegexp converts glob ('**' matches any number of directories) to regular expression.
+*/
+func globToRegexp(glob string) string {
+	var expression strings.Builder
+	for i := 0; i < len(glob); i++ {
+		char := glob[i]
+		switch {
+		case strings.HasPrefix(glob[i:], "**/"):
+			expression.WriteString("(.*/)?")
+			i += 2
+		case strings.HasPrefix(glob[i:], "**"):
+			expression.WriteString(".*")
+			i++
+		case char == '*':
+			expression.WriteString("[^/]*")
+		case char == '?':
+			expression.WriteString("[^/]")
+		case char == '[':
+			end := strings.IndexByte(glob[i:], ']')
+			if end < 0 {
+				expression.WriteString(regexp.QuoteMeta(string(char)))
+				continue
+			}
+			class := glob[i+1 : i+end]
+			if strings.HasPrefix(class, "!") {
+				class = "^" + class[1:]
+			}
+			expression.WriteString("[" + class + "]")
+			i += end
+		default:
+			expression.WriteString(regexp.QuoteMeta(string(char)))
+		}
+	}
+	return expression.String()
+}
+
+/*
+isBinaryFile checks if file is binary (contains NUL bytes) and not a media type supported by Gemini (image, audio, video, pdf).
+*/
+func isBinaryFile(filename string) bool {
+	mimeType, err := getMimeType(filename)
+	if err != nil {
+		return false
+	}
+	if strings.HasPrefix(mimeType, "text/") || strings.HasPrefix(mimeType, "image/") || strings.HasPrefix(mimeType, "audio/") ||
+		strings.HasPrefix(mimeType, "video/") || mimeType == "application/pdf" {
+		return false
+	}
+
+	file, err := os.Open(filename)
+	if err != nil {
+		return false
+	}
+	defer file.Close()
+
+	buffer := make([]byte, binaryDetectionSize)
+	n, _ := file.Read(buffer)
+	return bytes.IndexByte(buffer[:n], 0) >= 0
+}
+
+/*
+printResolvedFiles prints resolved files (status, transfer strategy, converter, MIME type, size) and skipped files
+without contacting Gemini. Returns markdown of converted documents (key: filename).
+*/
+func printResolvedFiles(allFiles []string, skippedFiles []SkippedFile) map[string]string {
+	convertedFiles := map[string]string{}
+
+	fmt.Printf("\nFiles given via command line (resolved):\n")
+	totalSize := int64(0)
+	for _, file := range allFiles {
+		// documents are converted locally (shown values refer to converted markdown)
+		if converter, ok := findDocumentConverter(file); ok {
+			markdown, err := convertDocument(file, converter)
+			if err != nil {
+				fmt.Printf("  %-5s  %-6s  %-5s  [%v] %s\n", "error", "-", converter.Name, err, file)
+				continue
+			}
+			convertedFiles[file] = markdown
+			size := int64(len(markdown))
+			totalSize += size
+			strategy := "upload"
+			if isInlineSize(size, true) {
+				strategy = "inline"
+			}
+			fmt.Printf("  %-5s  %-6s  %-5s  %-32.32s  %12s  %s\n", "ok", strategy, converter.Name, "text/markdown",
+				fmt.Sprintf("%.1f KiB", float64(size)/1024.0), file)
+			continue
+		}
+
+		mimeType, err := getMimeType(file)
+		info := "ok"
+		if err != nil {
+			info = "error"
+		}
+		if mimeType == "application/octet-stream" {
+			info = "warn"
+		}
+		if err == nil {
+			size := "-"
+			if fileInfo, err := os.Stat(file); err == nil {
+				size = fmt.Sprintf("%.1f KiB", float64(fileInfo.Size())/1024.0)
+				totalSize += fileInfo.Size()
+			}
+			strategy := "upload"
+			if isInlineCandidate(file) {
+				strategy = "inline"
+			}
+			fmt.Printf("  %-5s  %-6s  %-5s  %-32.32s  %12s  %s\n", info, strategy, "-", mimeType, size, file)
+		} else {
+			fmt.Printf("  %-5s  %s\n", info, err)
+		}
+	}
+	if len(skippedFiles) > 0 {
+		fmt.Printf("\nSkipped files:\n")
+		for _, skippedFile := range skippedFiles {
+			fmt.Printf("  %-24s  %s\n", skippedFile.Reason, skippedFile.Path)
+		}
+	}
+	fmt.Printf("\nTotal files : %d (%.1f KiB)\n", len(allFiles), float64(totalSize)/1024.0)
+
+	return convertedFiles
+}
@@ -0,0 +1,102 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"slices"
+	"strconv"
+	"time"
+
+	"google.golang.org/api/googleapi"
+)
+
+// error classes which can be used in retry policy
+var retryableErrorClasses = []string{"quota", "overloaded", "internal", "timeout", "other"}
+
+/*
+withRetry calls operation until it succeeds, fails with a non-retryable error or max attempts are reached.
+Returns number of attempts made.
+*/
+func withRetry(ctx context.Context, operation string, fn func() error) (int, error) {
+	attempt := 0
+	for {
+		attempt++
+		err := fn()
+		if err == nil {
+			return attempt, nil
+		}
+
+		errorClass := classifyError(err)
+		if attempt >= progConfig.RetryMaxAttempts || !slices.Contains(progConfig.RetryErrorClasses, errorClass) {
+			return attempt, err
+		}
+
+		delay := retryDelay(err, attempt)
+		fmt.Printf("error [%v] %s (attempt %d of %d, class %s), retrying in %.1f secs ...\n",
+			err, operation, attempt, progConfig.RetryMaxAttempts, errorClass, delay.Seconds())
+		select {
+		case <-ctx.Done():
+			return attempt, ctx.Err()
+		case <-time.After(delay):
+		}
+	}
+}
+
+/*
+retryDelay calculates delay before next attempt (exponential backoff with jitter, delay requested by API has precedence).
+*/
+func retryDelay(err error, attempt int) time.Duration {
+	if delay, ok := apiRetryDelay(err); ok {
+		return delay
+	}
+
+	delay := time.Duration(progConfig.RetryInitialDelay * float64(time.Second))
+	for i := 1; i < attempt; i++ {
+		delay *= 2
+	}
+	maxDelay := time.Duration(progConfig.RetryMaxDelay * float64(time.Second))
+	if delay > maxDelay {
+		delay = maxDelay
+	}
+
+	// jitter: 50-100% of calculated delay
+	return delay/2 + time.Duration(rand.Int64N(int64(delay/2)+1))
+}
+
+/*
+apiRetryDelay extracts retry delay returned by API (RetryInfo details or Retry-After header).
+*/
+func apiRetryDelay(err error) (time.Duration, bool) {
+	var apiError *googleapi.Error
+	if !errors.As(err, &apiError) {
+		return 0, false
+	}
+
+	// e.g. {"@type": "type.googleapis.com/google.rpc.RetryInfo", "retryDelay": "37s"}
+	for _, detail := range apiError.Details {
+		fields, ok := detail.(map[string]any)
+		if !ok || fields["@type"] != "type.googleapis.com/google.rpc.RetryInfo" {
+			continue
+		}
+		retryDelay, ok := fields["retryDelay"].(string)
+		if !ok {
+			continue
+		}
+		delay, err := time.ParseDuration(retryDelay)
+		if err == nil {
+			return delay, true
+		}
+	}
+
+	// e.g. 'Retry-After: 30'
+	if apiError.Header != nil {
+		seconds, err := strconv.Atoi(apiError.Header.Get("Retry-After"))
+		if err == nil {
+			return time.Duration(seconds) * time.Second, true
+		}
+	}
+
+	return 0, false
+}
@@ -0,0 +1,143 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/google/generative-ai-go/genai"
+)
+
+// harm categories configurable in SafetySettings (lower case)
+var safetyCategories = map[string]genai.HarmCategory{
+	"harassment":       genai.HarmCategoryHarassment,
+	"hatespeech":       genai.HarmCategoryHateSpeech,
+	"sexuallyexplicit": genai.HarmCategorySexuallyExplicit,
+	"dangerouscontent": genai.HarmCategoryDangerousContent,
+}
+
+// blocking thresholds configurable in SafetySettings ('default' = threshold of AI model)
+var safetyThresholds = map[string]genai.HarmBlockThreshold{
+	"default": genai.HarmBlockUnspecified,
+	"none":    genai.HarmBlockNone,
+	"low":     genai.HarmBlockLowAndAbove,
+	"medium":  genai.HarmBlockMediumAndAbove,
+	"high":    genai.HarmBlockOnlyHigh,
+}
+
+// safety ratings code block rendered as collapsible section in html
+var safetyRatingsPattern = regexp.MustCompile(`(?s)<pre><code class="language-safety">(.*?)</code></pre>`)
+
+/*
+buildSafetySettings builds safety settings from configuration (categories with threshold 'default' are omitted).
+*/
+func buildSafetySettings() ([]*genai.SafetySetting, error) {
+	safetySettings := []*genai.SafetySetting{}
+	for name, value := range progConfig.SafetySettings {
+		category, ok := safetyCategories[strings.ToLower(name)]
+		if !ok {
+			return nil, fmt.Errorf("unsupported safety category [%s] (possible options: Harassment, HateSpeech, SexuallyExplicit, DangerousContent)", name)
+		}
+		threshold, ok := safetyThresholds[strings.ToLower(value)]
+		if !ok {
+			return nil, fmt.Errorf("unsupported safety threshold [%s] for category [%s] (possible options: default, none, low, medium, high)", value, name)
+		}
+		if threshold == genai.HarmBlockUnspecified {
+			continue
+		}
+		safetySettings = append(safetySettings, &genai.SafetySetting{Category: category, Threshold: threshold})
+	}
+	sort.Slice(safetySettings, func(i, j int) bool { return safetySettings[i].Category < safetySettings[j].Category })
+	return safetySettings, nil
+}
+
+/*
+formatSafetySettings formats safety settings as readable list (e.g. 'Harassment: medium').
+*/
+func formatSafetySettings(safetySettings []*genai.SafetySetting) string {
+	thresholdNames := map[genai.HarmBlockThreshold]string{}
+	for name, threshold := range safetyThresholds {
+		thresholdNames[threshold] = name
+	}
+	settings := []string{}
+	for _, safetySetting := range safetySettings {
+		settings = append(settings, fmt.Sprintf("%s: %s", harmCategoryName(safetySetting.Category), thresholdNames[safetySetting.Threshold]))
+	}
+	return strings.Join(settings, ", ")
+}
+
+/*
+harmCategoryName gets readable name of harm category (e.g. 'HateSpeech').
+*/
+func harmCategoryName(category genai.HarmCategory) string {
+	return strings.TrimPrefix(category.String(), "HarmCategory")
+}
+
+/*
+formatSafetyRatings formats safety ratings (category, probability, blocked) as markdown code block.
+In html, the code block is rendered as collapsible section.
+*/
+func formatSafetyRatings(title string, safetyRatings []*genai.SafetyRating) string {
+	if len(safetyRatings) == 0 {
+		return ""
+	}
+
+	var ratings strings.Builder
+	ratings.WriteString("```safety\n")
+	ratings.WriteString(fmt.Sprintf("%s:\n", title))
+	for _, safetyRating := range safetyRatings {
+		blocked := ""
+		if safetyRating.Blocked {
+			blocked = " (blocked)"
+		}
+		ratings.WriteString(fmt.Sprintf("  %-18s : %s%s\n", harmCategoryName(safetyRating.Category),
+			strings.ToLower(strings.TrimPrefix(safetyRating.Probability.String(), "HarmProbability")), blocked))
+	}
+	ratings.WriteString("```\n")
+	return ratings.String()
+}
+
+/*
+explainBlockedError explains why prompt or response has been blocked (incl. safety ratings) as markdown.
+*/
+func explainBlockedError(blockedError *genai.BlockedError) string {
+	var explanation strings.Builder
+
+	if blockedError.PromptFeedback != nil {
+		switch blockedError.PromptFeedback.BlockReason {
+		case genai.BlockReasonSafety:
+			explanation.WriteString("The prompt was blocked by Gemini because it (or the data attached to it) was rated as potentially harmful. ")
+			explanation.WriteString("Rephrase the prompt or adjust the thresholds in 'SafetySettings'.\n\n")
+		default:
+			explanation.WriteString(fmt.Sprintf("The prompt was blocked by Gemini with reason [%s] (e.g. terms of service, unsupported content).\n\n",
+				strings.TrimPrefix(blockedError.PromptFeedback.BlockReason.String(), "BlockReason")))
+		}
+		explanation.WriteString(formatSafetyRatings("Safety Ratings (Prompt)", blockedError.PromptFeedback.SafetyRatings))
+	}
+
+	if blockedError.Candidate != nil {
+		switch blockedError.Candidate.FinishReason {
+		case genai.FinishReasonSafety:
+			explanation.WriteString("The response was blocked by Gemini because it was rated as potentially harmful (see blocked categories). ")
+			explanation.WriteString("Rephrase the prompt or adjust the thresholds in 'SafetySettings'.\n\n")
+		case genai.FinishReasonRecitation:
+			explanation.WriteString("The response was blocked by Gemini because it recites existing content (e.g. copyrighted text or code) too closely. ")
+			explanation.WriteString("Rephrase the prompt (e.g. ask for a summary instead of the original text).\n\n")
+		default:
+			explanation.WriteString(fmt.Sprintf("The response was blocked by Gemini with reason [%s].\n\n",
+				strings.TrimPrefix(blockedError.Candidate.FinishReason.String(), "FinishReason")))
+		}
+		explanation.WriteString(formatSafetyRatings("Safety Ratings (Response)", blockedError.Candidate.SafetyRatings))
+	}
+
+	return explanation.String()
+}
+
+/*
+collapseSafetyRatings renders safety ratings code blocks in html as collapsible sections.
+*/
+func collapseSafetyRatings(html string) string {
+	return safetyRatingsPattern.ReplaceAllString(html,
+		`<details class="safety-ratings"><summary>Safety Ratings</summary><pre><code class="language-plaintext">$1</code></pre></details>`)
+}
@@ -0,0 +1,119 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/google/generative-ai-go/genai"
+)
+
+// schema types (JSON schema subset supported by Gemini)
+var schemaTypes = map[string]genai.Type{
+	"string":  genai.TypeString,
+	"number":  genai.TypeNumber,
+	"integer": genai.TypeInteger,
+	"boolean": genai.TypeBoolean,
+	"array":   genai.TypeArray,
+	"object":  genai.TypeObject,
+}
+
+/*
+schemaFromMap converts JSON schema (subset: type, format, description, nullable, enum, items, properties, required)
+given as map (e.g. from YAML or JSON) into genai schema.
+*/
+func schemaFromMap(value map[string]any) (*genai.Schema, error) {
+	schema := &genai.Schema{}
+
+	typeName, ok := value["type"].(string)
+	if !ok {
+		return nil, fmt.Errorf("schema type missing or not a string")
+	}
+	schema.Type, ok = schemaTypes[typeName]
+	if !ok {
+		return nil, fmt.Errorf("unsupported schema type [%s]", typeName)
+	}
+	if format, ok := value["format"].(string); ok {
+		schema.Format = format
+	}
+	if description, ok := value["description"].(string); ok {
+		schema.Description = description
+	}
+	if nullable, ok := value["nullable"].(bool); ok {
+		schema.Nullable = nullable
+	}
+
+	if enum, ok := value["enum"]; ok {
+		items, ok := enum.([]any)
+		if !ok {
+			return nil, fmt.Errorf("schema enum not a list")
+		}
+		for _, item := range items {
+			schema.Enum = append(schema.Enum, fmt.Sprintf("%v", item))
+		}
+	}
+
+	if items, ok := value["items"]; ok {
+		itemsMap, ok := items.(map[string]any)
+		if !ok {
+			return nil, fmt.Errorf("schema items not an object")
+		}
+		itemsSchema, err := schemaFromMap(itemsMap)
+		if err != nil {
+			return nil, fmt.Errorf("items: %w", err)
+		}
+		schema.Items = itemsSchema
+	}
+	if schema.Type == genai.TypeArray && schema.Items == nil {
+		return nil, fmt.Errorf("schema items of array missing")
+	}
+
+	if properties, ok := value["properties"]; ok {
+		propertiesMap, ok := properties.(map[string]any)
+		if !ok {
+			return nil, fmt.Errorf("schema properties not an object")
+		}
+		schema.Properties = map[string]*genai.Schema{}
+		for name, property := range propertiesMap {
+			propertyMap, ok := property.(map[string]any)
+			if !ok {
+				return nil, fmt.Errorf("schema property [%s] not an object", name)
+			}
+			propertySchema, err := schemaFromMap(propertyMap)
+			if err != nil {
+				return nil, fmt.Errorf("property [%s]: %w", name, err)
+			}
+			schema.Properties[name] = propertySchema
+		}
+	}
+
+	if required, ok := value["required"]; ok {
+		items, ok := required.([]any)
+		if !ok {
+			return nil, fmt.Errorf("schema required not a list")
+		}
+		for _, item := range items {
+			name, ok := item.(string)
+			if !ok {
+				return nil, fmt.Errorf("schema required entry not a string")
+			}
+			if _, ok := schema.Properties[name]; !ok {
+				return nil, fmt.Errorf("required property [%s] not defined", name)
+			}
+			schema.Required = append(schema.Required, name)
+		}
+	}
+
+	return schema, nil
+}
+
+/*
+schemaPropertyNames gets sorted property names of object schema.
+*/
+func schemaPropertyNames(schema *genai.Schema) []string {
+	names := []string{}
+	for name := range schema.Properties {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
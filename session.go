@@ -0,0 +1,427 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/google/generative-ai-go/genai"
+)
+
+// ChatSessionData represents a persisted chat session (serialized as JSON)
+type ChatSessionData struct {
+	Name              string                 `json:"name"`
+	Created           time.Time              `json:"created"`
+	Updated           time.Time              `json:"updated"`
+	Model             string                 `json:"model"`
+	GenerationConfig  genai.GenerationConfig `json:"generationConfig"`
+	SystemInstruction string                 `json:"systemInstruction,omitempty"`
+	Files             []ChatSessionFile      `json:"files,omitempty"`
+	History           []ChatSessionContent   `json:"history"`
+	Transcript        []string               `json:"transcript"`
+}
+
+// ChatSessionFile represents a reference to an uploaded file used in a chat session
+type ChatSessionFile struct {
+	Name           string    `json:"name"`
+	URI            string    `json:"uri"`
+	DisplayName    string    `json:"displayName"`
+	MIMEType       string    `json:"mimeType"`
+	SizeBytes      int64     `json:"sizeBytes"`
+	ExpirationTime time.Time `json:"expirationTime"`
+	HistoryOnly    bool      `json:"historyOnly,omitempty"` // referenced by chat history only (detached, replaced, prompt directive)
+}
+
+// ChatSessionContent represents one content (user or model) of chat history
+type ChatSessionContent struct {
+	Role  string            `json:"role"`
+	Parts []ChatSessionPart `json:"parts"`
+}
+
+// ChatSessionPart represents one part of a content of chat history
+type ChatSessionPart struct {
+	Type     string         `json:"type"`
+	Text     string         `json:"text,omitempty"`
+	MIMEType string         `json:"mimeType,omitempty"`
+	Data     []byte         `json:"data,omitempty"`
+	URI      string         `json:"uri,omitempty"`
+	Name     string         `json:"name,omitempty"`
+	Args     map[string]any `json:"args,omitempty"`
+	Language int32          `json:"language,omitempty"`
+	Outcome  int32          `json:"outcome,omitempty"`
+}
+
+// name of current chat session (used for persistence)
+var chatSessionName string
+
+// creation time of current chat session
+var chatSessionCreated time.Time
+
+// remote files referenced by chat history (key: URI), known even after they are detached or replaced
+var chatHistoryFiles = map[string]ChatSessionFile{}
+
+// generation parameters given via command line (precedence: command line, resumed chat session, configuration)
+var commandLineGenerationConfig genai.GenerationConfig
+
+// valid chat session name
+var validChatSessionName = regexp.MustCompile(`^[a-zA-Z0-9._-]+$`)
+
+/*
+buildChatSessionName builds default chat session name from given timestamp.
+*/
+func buildChatSessionName(now time.Time) string {
+	return "chat-" + now.Format("20060102-150405")
+}
+
+/*
+chatSessionPathname builds pathname of chat session file.
+*/
+func chatSessionPathname(name string) string {
+	return filepath.Join(progConfig.ChatSessionDirectory, name+".json")
+}
+
+/*
+saveChatSession saves current chat session (history, files, model configuration) to session file.
+*/
+func saveChatSession(geminiModel *genai.GenerativeModel) error {
+	if chatSession == nil || chatSessionName == "" {
+		return nil
+	}
+
+	sessionData := ChatSessionData{
+		Name:              chatSessionName,
+		Created:           chatSessionCreated,
+		Updated:           time.Now(),
+		Model:             progConfig.GeminiAiModel,
+		GenerationConfig:  geminiModel.GenerationConfig,
+		SystemInstruction: progConfig.GeminiSystemInstruction,
+		Transcript:        chatTranscript,
+	}
+	for _, uploadedFile := range uploadedFiles {
+		sessionData.Files = append(sessionData.Files, newChatSessionFile(uploadedFile, false))
+	}
+	sessionData.Files = append(sessionData.Files, chatHistoryOnlyFiles(sessionData.Files)...)
+	for _, content := range chatSession.History {
+		sessionData.History = append(sessionData.History, contentToSession(content))
+	}
+
+	return writeChatSession(sessionData)
+}
+
+/*
+newChatSessionFile builds chat session reference to uploaded file and remembers it as possibly used by chat history.
+*/
+func newChatSessionFile(file *genai.File, historyOnly bool) ChatSessionFile {
+	sessionFile := ChatSessionFile{
+		Name:           file.Name,
+		URI:            file.URI,
+		DisplayName:    file.DisplayName,
+		MIMEType:       file.MIMEType,
+		SizeBytes:      file.SizeBytes,
+		ExpirationTime: file.ExpirationTime,
+		HistoryOnly:    historyOnly,
+	}
+	chatHistoryFiles[file.URI] = sessionFile
+	return sessionFile
+}
+
+/*
+chatHistoryOnlyFiles gets files referenced by chat history which are not part of the attachment set
+(uploaded by prompt directives, detached or replaced by newer version). Without them in session file,
+such files cannot be re-uploaded when the chat session is resumed.
+*/
+func chatHistoryOnlyFiles(attachedFiles []ChatSessionFile) []ChatSessionFile {
+	for _, promptUploadedFile := range promptUploadedFiles {
+		newChatSessionFile(promptUploadedFile, true)
+	}
+
+	listed := map[string]bool{}
+	for _, attachedFile := range attachedFiles {
+		listed[attachedFile.URI] = true
+	}
+	files := []ChatSessionFile{}
+	for _, content := range chatSession.History {
+		for _, part := range content.Parts {
+			fileData, ok := part.(genai.FileData)
+			if !ok || listed[fileData.URI] {
+				continue
+			}
+			listed[fileData.URI] = true
+			sessionFile, ok := chatHistoryFiles[fileData.URI]
+			if !ok {
+				fmt.Printf("warning: remote file [%s] of chat history unknown, not saved in chat session\n", fileData.URI)
+				continue
+			}
+			sessionFile.HistoryOnly = true
+			files = append(files, sessionFile)
+		}
+	}
+	return files
+}
+
+/*
+writeChatSession writes chat session data to session file.
+*/
+func writeChatSession(sessionData ChatSessionData) error {
+	data, err := json.MarshalIndent(sessionData, "", "  ")
+	if err != nil {
+		return fmt.Errorf("error [%w] marshalling chat session", err)
+	}
+	err = os.WriteFile(chatSessionPathname(sessionData.Name), data, 0644)
+	if err != nil {
+		return fmt.Errorf("error [%w] writing chat session file", err)
+	}
+	return nil
+}
+
+/*
+loadChatSession loads named chat session from session file.
+*/
+func loadChatSession(name string) (ChatSessionData, error) {
+	var sessionData ChatSessionData
+
+	data, err := os.ReadFile(chatSessionPathname(name))
+	if err != nil {
+		return sessionData, fmt.Errorf("error [%w] reading chat session file", err)
+	}
+	err = json.Unmarshal(data, &sessionData)
+	if err != nil {
+		return sessionData, fmt.Errorf("error [%w] unmarshalling chat session file", err)
+	}
+	return sessionData, nil
+}
+
+/*
+chatSessionExists checks if named chat session exists.
+*/
+func chatSessionExists(name string) bool {
+	return fileExists(chatSessionPathname(name))
+}
+
+/*
+resumeChatSession restores named chat session as current chat.
+*/
+func resumeChatSession(ctx context.Context, client *genai.Client, geminiModel *genai.GenerativeModel, name string) error {
+	sessionData, err := loadChatSession(name)
+	if err != nil {
+		return err
+	}
+
+	if sessionData.Model != progConfig.GeminiAiModel {
+		fmt.Printf("note: chat session was created with AI model [%s], continuing with [%s]\n", sessionData.Model, progConfig.GeminiAiModel)
+	}
+	geminiModel.GenerationConfig = mergeGenerationConfig(sessionData.GenerationConfig, commandLineGenerationConfig)
+
+	refreshChatSessionFiles(ctx, client, &sessionData)
+
+	startNewChat(geminiModel)
+	for _, content := range sessionData.History {
+		chatSession.History = append(chatSession.History, contentFromSession(content))
+	}
+	chatTranscript = sessionData.Transcript
+	chatSessionName = sessionData.Name
+	chatSessionCreated = sessionData.Created
+
+	return nil
+}
+
+/*
+mergeGenerationConfig overwrites generation parameters of chat session with parameters given via command line.
+*/
+func mergeGenerationConfig(sessionConfig, commandLineConfig genai.GenerationConfig) genai.GenerationConfig {
+	if commandLineConfig.CandidateCount != nil {
+		sessionConfig.CandidateCount = commandLineConfig.CandidateCount
+	}
+	if commandLineConfig.Temperature != nil {
+		sessionConfig.Temperature = commandLineConfig.Temperature
+	}
+	if commandLineConfig.TopP != nil {
+		sessionConfig.TopP = commandLineConfig.TopP
+	}
+	if commandLineConfig.TopK != nil {
+		sessionConfig.TopK = commandLineConfig.TopK
+	}
+	if commandLineConfig.MaxOutputTokens != nil {
+		sessionConfig.MaxOutputTokens = commandLineConfig.MaxOutputTokens
+	}
+	return sessionConfig
+}
+
+/*
+forkChatSession creates new chat session from first turns of current chat and continues with it.
+*/
+func forkChatSession(geminiModel *genai.GenerativeModel, turn int, name string) error {
+	turns := countChatTurns()
+	if turn < 1 || turn > turns {
+		return fmt.Errorf("turn [%d] out of range (1-%d)", turn, turns)
+	}
+
+	// chat history alternates between user and model content (one pair per turn)
+	history := chatSession.History[:2*turn]
+	transcript := chatTranscript[:turn]
+
+	startNewChat(geminiModel)
+	chatSession.History = append(chatSession.History, history...)
+	chatTranscript = append(chatTranscript, transcript...)
+	chatSessionName = name
+	chatSessionCreated = time.Now()
+
+	return saveChatSession(geminiModel)
+}
+
+/*
+refreshChatSessionFiles re-uploads files referenced by chat session which no longer exist remotely.
+*/
+func refreshChatSessionFiles(ctx context.Context, client *genai.Client, sessionData *ChatSessionData) {
+	replacedURIs := map[string]string{}
+	for i, sessionFile := range sessionData.Files {
+		_, err := client.GetFile(ctx, sessionFile.Name)
+		if err == nil {
+			chatHistoryFiles[sessionFile.URI] = sessionFile
+			continue
+		}
+		if !fileExists(sessionFile.DisplayName) {
+			fmt.Printf("warning: remote file [%s] of chat session no longer available\n", sessionFile.DisplayName)
+			continue
+		}
+
+		// local file already attached (uploaded with current content), history refers to attached remote file
+		uploadedFilesMu.Lock()
+		index := findAttachedFile(sessionFile.DisplayName)
+		var file *genai.File
+		if index >= 0 && index < len(uploadedFiles) {
+			file = uploadedFiles[index]
+		}
+		uploadedFilesMu.Unlock()
+
+		if file == nil {
+			fmt.Printf("re-uploading file [%s] of chat session ...\n", sessionFile.DisplayName)
+			files, err := uploadFilesToGemini(ctx, client, []string{sessionFile.DisplayName})
+			if err != nil || len(files) == 0 {
+				fmt.Printf("warning: unable to re-upload file [%s] of chat session\n", sessionFile.DisplayName)
+				continue
+			}
+			file = files[0]
+			uploadedFilesMu.Lock()
+			if sessionFile.HistoryOnly {
+				// not attached, only needed by chat history
+				promptUploadedFiles = append(promptUploadedFiles, file)
+			} else {
+				uploadedFiles = append(uploadedFiles, file)
+			}
+			uploadedFilesMu.Unlock()
+		}
+		replacedURIs[sessionFile.URI] = file.URI
+		sessionData.Files[i].Name = file.Name
+		sessionData.Files[i].URI = file.URI
+		sessionData.Files[i].ExpirationTime = file.ExpirationTime
+		chatHistoryFiles[file.URI] = sessionData.Files[i]
+	}
+
+	// replace references to re-uploaded files in chat history
+	for _, content := range sessionData.History {
+		for j, part := range content.Parts {
+			if newURI, ok := replacedURIs[part.URI]; ok && part.Type == "filedata" {
+				content.Parts[j].URI = newURI
+			}
+		}
+	}
+}
+
+/*
+listChatSessions prints list of all saved chat sessions.
+*/
+func listChatSessions() {
+	pathnames, err := filepath.Glob(filepath.Join(progConfig.ChatSessionDirectory, "*.json"))
+	if err != nil {
+		fmt.Printf("error [%v] at filepath.Glob()\n", err)
+		return
+	}
+
+	sessions := []ChatSessionData{}
+	for _, pathname := range pathnames {
+		name := strings.TrimSuffix(filepath.Base(pathname), ".json")
+		sessionData, err := loadChatSession(name)
+		if err != nil {
+			fmt.Printf("error [%v] loading chat session [%s]\n", err, name)
+			continue
+		}
+		sessions = append(sessions, sessionData)
+	}
+	sort.Slice(sessions, func(i, j int) bool {
+		return sessions[i].Updated.After(sessions[j].Updated)
+	})
+
+	fmt.Printf("\nChat sessions (%s):\n", progConfig.ChatSessionDirectory)
+	if len(sessions) == 0 {
+		fmt.Printf("  none\n")
+	}
+	for _, sessionData := range sessions {
+		fmt.Printf("  %-32s  %s  %3d %-5s  %s\n", sessionData.Name, sessionData.Updated.Format("2006-01-02 15:04:05"),
+			len(sessionData.Transcript), pluralize(len(sessionData.Transcript), "turn"), sessionData.Model)
+	}
+	fmt.Printf("\n")
+}
+
+/*
+contentToSession converts genai content to serializable chat session content.
+*/
+func contentToSession(content *genai.Content) ChatSessionContent {
+	sessionContent := ChatSessionContent{Role: content.Role}
+	for _, part := range content.Parts {
+		var sessionPart ChatSessionPart
+		switch p := part.(type) {
+		case genai.Text:
+			sessionPart = ChatSessionPart{Type: "text", Text: string(p)}
+		case genai.Blob:
+			sessionPart = ChatSessionPart{Type: "blob", MIMEType: p.MIMEType, Data: p.Data}
+		case genai.FileData:
+			sessionPart = ChatSessionPart{Type: "filedata", MIMEType: p.MIMEType, URI: p.URI}
+		case genai.FunctionCall:
+			sessionPart = ChatSessionPart{Type: "functioncall", Name: p.Name, Args: p.Args}
+		case genai.FunctionResponse:
+			sessionPart = ChatSessionPart{Type: "functionresponse", Name: p.Name, Args: p.Response}
+		case *genai.ExecutableCode:
+			sessionPart = ChatSessionPart{Type: "executablecode", Text: p.Code, Language: int32(p.Language)}
+		case *genai.CodeExecutionResult:
+			sessionPart = ChatSessionPart{Type: "codeexecutionresult", Text: p.Output, Outcome: int32(p.Outcome)}
+		default:
+			fmt.Printf("warning: unsupported part type [%T] not saved in chat session\n", part)
+			continue
+		}
+		sessionContent.Parts = append(sessionContent.Parts, sessionPart)
+	}
+	return sessionContent
+}
+
+/*
+contentFromSession converts chat session content to genai content.
+*/
+func contentFromSession(sessionContent ChatSessionContent) *genai.Content {
+	content := &genai.Content{Role: sessionContent.Role}
+	for _, p := range sessionContent.Parts {
+		switch p.Type {
+		case "text":
+			content.Parts = append(content.Parts, genai.Text(p.Text))
+		case "blob":
+			content.Parts = append(content.Parts, genai.Blob{MIMEType: p.MIMEType, Data: p.Data})
+		case "filedata":
+			content.Parts = append(content.Parts, genai.FileData{MIMEType: p.MIMEType, URI: p.URI})
+		case "functioncall":
+			content.Parts = append(content.Parts, genai.FunctionCall{Name: p.Name, Args: p.Args})
+		case "functionresponse":
+			content.Parts = append(content.Parts, genai.FunctionResponse{Name: p.Name, Response: p.Args})
+		case "executablecode":
+			content.Parts = append(content.Parts, &genai.ExecutableCode{Code: p.Text, Language: genai.ExecutableCodeLanguage(p.Language)})
+		case "codeexecutionresult":
+			content.Parts = append(content.Parts, &genai.CodeExecutionResult{Output: p.Text, Outcome: genai.CodeExecutionResultOutcome(p.Outcome)})
+		}
+	}
+	return content
+}
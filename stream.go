@@ -0,0 +1,92 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/google/generative-ai-go/genai"
+	"google.golang.org/api/iterator"
+)
+
+/*
+generateContentStream generates content in streaming mode and prints text to terminal as it arrives.
+*/
+func generateContentStream(ctx context.Context, geminiModel *genai.GenerativeModel, promptParts []genai.Part) (*genai.GenerateContentResponse, error) {
+	var iter *genai.GenerateContentResponseIterator
+	historyLength := 0
+	if chatSession != nil {
+		historyLength = len(chatSession.History)
+		iter = chatSession.SendMessageStream(ctx, promptParts...)
+	} else {
+		iter = geminiModel.GenerateContentStream(ctx, promptParts...)
+	}
+
+	// merged response doesn't contain final usage metadata (last chunk has it)
+	var usageMetadata *genai.UsageMetadata
+	var err error
+
+	fmt.Printf("\n")
+	for {
+		var chunk *genai.GenerateContentResponse
+		chunk, err = iter.Next()
+		if errors.Is(err, iterator.Done) {
+			err = nil
+			break
+		}
+		if err != nil {
+			break
+		}
+		if chunk.UsageMetadata != nil {
+			usageMetadata = chunk.UsageMetadata
+		}
+		handleStreamChunk(chunk)
+	}
+	fmt.Printf("\n\n")
+
+	if chatSession != nil {
+		if err != nil {
+			chatSession.History = chatSession.History[:historyLength]
+		}
+		removeUnansweredChatPrompt(historyLength)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	resp := iter.MergedResponse()
+	if resp == nil {
+		return nil, errors.New("empty response from model")
+	}
+	if usageMetadata != nil {
+		resp.UsageMetadata = usageMetadata
+	}
+
+	return resp, nil
+}
+
+/*
+handleStreamChunk handles chunk of streamed response (text and executed code of first candidate are printed to terminal and live view).
+*/
+func handleStreamChunk(chunk *genai.GenerateContentResponse) {
+	if len(chunk.Candidates) == 0 || chunk.Candidates[0].Content == nil {
+		return
+	}
+	for _, part := range chunk.Candidates[0].Content.Parts {
+		text := ""
+		switch p := part.(type) {
+		case genai.Text:
+			text = string(p)
+		case *genai.ExecutableCode:
+			text = "\n" + formatExecutableCode(p)
+		case *genai.CodeExecutionResult:
+			text = "\n" + formatCodeExecutionResult(p)
+		default:
+			continue
+		}
+		fmt.Print(text)
+		if liveView != nil {
+			liveView.AppendChunk(text)
+		}
+	}
+}
@@ -0,0 +1,240 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/google/generative-ai-go/genai"
+)
+
+// ResponseSchema represents JSON schema of structured (JSON) response
+type ResponseSchema struct {
+	Filename string
+	Schema   *genai.Schema
+}
+
+// response schema from configuration (nil = text response)
+var defaultResponseSchema *ResponseSchema
+
+// response schema of current prompt (configuration or '@schema:' directive)
+var activeResponseSchema *ResponseSchema
+
+/*
+loadResponseSchema reads JSON schema file and converts it into genai schema.
+*/
+func loadResponseSchema(filename string) (*ResponseSchema, error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	definition := map[string]any{}
+	err = json.Unmarshal(data, &definition)
+	if err != nil {
+		return nil, fmt.Errorf("schema file [%s]: %w", filename, err)
+	}
+	schema, err := schemaFromMap(definition)
+	if err != nil {
+		return nil, fmt.Errorf("schema file [%s]: %w", filename, err)
+	}
+	return &ResponseSchema{Filename: filename, Schema: schema}, nil
+}
+
+/*
+applyResponseSchema sets JSON output with given schema (nil = text output) for AI model and all fallback models.
+*/
+func applyResponseSchema(geminiModel *genai.GenerativeModel, responseSchema *ResponseSchema) {
+	models := []*genai.GenerativeModel{geminiModel}
+	for _, fallbackModel := range fallbackModels {
+		models = append(models, fallbackModel.model)
+	}
+	for _, model := range models {
+		if responseSchema == nil {
+			model.GenerationConfig.ResponseMIMEType = ""
+			model.GenerationConfig.ResponseSchema = nil
+			continue
+		}
+		model.GenerationConfig.ResponseMIMEType = "application/json"
+		model.GenerationConfig.ResponseSchema = responseSchema.Schema
+	}
+	activeResponseSchema = responseSchema
+}
+
+/*
+promptResponseSchema gets response schema for prompt (last '@schema:' directive overrides configuration).
+*/
+func promptResponseSchema(directives []PromptDirective) *ResponseSchema {
+	responseSchema := defaultResponseSchema
+	for _, directive := range directives {
+		if directive.Kind != "schema" {
+			continue
+		}
+		schema, err := loadResponseSchema(directive.Argument)
+		if err != nil {
+			fmt.Printf("error [%v] loading response schema, [%s] ignored\n", err, directive.Argument)
+			continue
+		}
+		responseSchema = schema
+	}
+	return responseSchema
+}
+
+/*
+candidateText gets text parts of response candidate (JSON document in structured output mode).
+*/
+func candidateText(candidate *genai.Candidate) string {
+	var text strings.Builder
+	if candidate == nil || candidate.Content == nil {
+		return ""
+	}
+	for _, part := range candidate.Content.Parts {
+		if p, ok := part.(genai.Text); ok {
+			text.WriteString(string(p))
+		}
+	}
+	return text.String()
+}
+
+/*
+validateJSONValue validates decoded JSON value against schema and returns validation errors (with JSON path).
+*/
+func validateJSONValue(value any, schema *genai.Schema, path string) []string {
+	if value == nil {
+		if schema.Nullable {
+			return nil
+		}
+		return []string{fmt.Sprintf("%s: null not allowed", path)}
+	}
+
+	validationErrors := []string{}
+	switch schema.Type {
+	case genai.TypeString:
+		s, ok := value.(string)
+		if !ok {
+			return []string{fmt.Sprintf("%s: expected string, got %s", path, jsonTypeName(value))}
+		}
+		if len(schema.Enum) > 0 && !slices.Contains(schema.Enum, s) {
+			validationErrors = append(validationErrors, fmt.Sprintf("%s: value [%s] not in enum (%s)", path, s, strings.Join(schema.Enum, ", ")))
+		}
+	case genai.TypeNumber:
+		if _, ok := value.(float64); !ok {
+			return []string{fmt.Sprintf("%s: expected number, got %s", path, jsonTypeName(value))}
+		}
+	case genai.TypeInteger:
+		number, ok := value.(float64)
+		if !ok || number != math.Trunc(number) {
+			return []string{fmt.Sprintf("%s: expected integer, got %s", path, jsonTypeName(value))}
+		}
+	case genai.TypeBoolean:
+		if _, ok := value.(bool); !ok {
+			return []string{fmt.Sprintf("%s: expected boolean, got %s", path, jsonTypeName(value))}
+		}
+	case genai.TypeArray:
+		items, ok := value.([]any)
+		if !ok {
+			return []string{fmt.Sprintf("%s: expected array, got %s", path, jsonTypeName(value))}
+		}
+		for i, item := range items {
+			validationErrors = append(validationErrors, validateJSONValue(item, schema.Items, fmt.Sprintf("%s[%d]", path, i))...)
+		}
+	case genai.TypeObject:
+		object, ok := value.(map[string]any)
+		if !ok {
+			return []string{fmt.Sprintf("%s: expected object, got %s", path, jsonTypeName(value))}
+		}
+		for _, name := range schema.Required {
+			if _, ok := object[name]; !ok {
+				validationErrors = append(validationErrors, fmt.Sprintf("%s: required property [%s] missing", path, name))
+			}
+		}
+		for _, name := range schemaPropertyNames(schema) {
+			if property, ok := object[name]; ok {
+				validationErrors = append(validationErrors, validateJSONValue(property, schema.Properties[name], path+"."+name)...)
+			}
+		}
+	}
+	return validationErrors
+}
+
+/*
+jsonTypeName gets JSON type name of decoded JSON value.
+*/
+func jsonTypeName(value any) string {
+	switch v := value.(type) {
+	case string:
+		return "string"
+	case float64:
+		if v == math.Trunc(v) {
+			return "integer"
+		}
+		return "number"
+	case bool:
+		return "boolean"
+	case []any:
+		return "array"
+	case map[string]any:
+		return "object"
+	}
+	return "null"
+}
+
+/*
+formatStructuredResponse formats JSON response as pretty-printed markdown code block followed by validation result.
+*/
+func formatStructuredResponse(text string) string {
+	var response strings.Builder
+
+	var value any
+	err := json.Unmarshal([]byte(text), &value)
+	if err != nil {
+		response.WriteString(fmt.Sprintf("```plaintext\n%s\n```\n\n", strings.TrimRight(text, "\n")))
+		response.WriteString(fmt.Sprintf("> **Schema Validation:** invalid JSON (%v)\n", err))
+		return response.String()
+	}
+
+	// indent keeps order of properties as generated by AI model
+	var pretty bytes.Buffer
+	_ = json.Indent(&pretty, []byte(strings.TrimSpace(text)), "", "  ")
+	response.WriteString(fmt.Sprintf("```json\n%s\n```\n\n", pretty.String()))
+
+	validationErrors := validateJSONValue(value, activeResponseSchema.Schema, "$")
+	if len(validationErrors) == 0 {
+		response.WriteString(fmt.Sprintf("Schema Validation: valid against schema `%s`\n", activeResponseSchema.Filename))
+		return response.String()
+	}
+	response.WriteString(fmt.Sprintf("> **Schema Validation:** %d %s against schema `%s`\n>\n", len(validationErrors),
+		pluralize(len(validationErrors), "error"), activeResponseSchema.Filename))
+	for _, validationError := range validationErrors {
+		response.WriteString(fmt.Sprintf("> * **%s**\n", validationError))
+	}
+	return response.String()
+}
+
+/*
+saveJSONHistory writes JSON response (first candidate, pretty-printed if valid) as history artifact.
+*/
+func saveJSONHistory(now time.Time, prompt, workingDirectory string, resp *genai.GenerateContentResponse) {
+	if activeResponseSchema == nil || !progConfig.JSONHistory || resp == nil || len(resp.Candidates) == 0 {
+		return
+	}
+	text := strings.TrimSpace(candidateText(resp.Candidates[0]))
+	data := []byte(text + "\n")
+	var pretty bytes.Buffer
+	if json.Indent(&pretty, []byte(text), "", "  ") == nil {
+		pretty.WriteString("\n")
+		data = pretty.Bytes()
+	}
+
+	jsonDestinationFile := buildDestinationFilename(now, prompt, progConfig.HistoryFilenameExtensionJSON)
+	jsonDestinationPathFile := filepath.Join(workingDirectory, progConfig.JSONHistoryDirectory, jsonDestinationFile)
+	err := os.WriteFile(jsonDestinationPathFile, data, 0644)
+	if err != nil {
+		fmt.Printf("error [%v] at os.WriteFile()\n", err)
+	}
+}
@@ -0,0 +1,190 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/google/generative-ai-go/genai"
+)
+
+// max size of file for inline token counting (request size limit)
+const maxInlineTokenCountSize = 20 * 1024 * 1024
+
+/*
+countPromptTokens counts tokens of prompt parts (incl. chat history and system instruction).
+*/
+func countPromptTokens(ctx context.Context, geminiModel *genai.GenerativeModel, promptParts []genai.Part) (int32, error) {
+	parts := []genai.Part{}
+	if chatSession != nil {
+		for _, content := range chatSession.History {
+			parts = append(parts, content.Parts...)
+		}
+	}
+	parts = append(parts, promptParts...)
+
+	resp, err := geminiModel.CountTokens(ctx, parts...)
+	if err != nil {
+		return 0, err
+	}
+	return resp.TotalTokens, nil
+}
+
+/*
+countUploadedFilesTokens counts tokens of all uploaded and inlined files.
+*/
+func countUploadedFilesTokens(ctx context.Context, geminiModel *genai.GenerativeModel) (int32, error) {
+	parts := []genai.Part{}
+	for _, uploadedFile := range uploadedFiles {
+		parts = append(parts, genai.FileData{URI: uploadedFile.URI})
+	}
+	for _, inlinedFile := range inlinedFiles {
+		parts = append(parts, inlinedFile.Parts...)
+	}
+	if len(parts) == 0 {
+		return 0, nil
+	}
+
+	resp, err := geminiModel.CountTokens(ctx, parts...)
+	if err != nil {
+		return 0, err
+	}
+	return resp.TotalTokens, nil
+}
+
+/*
+countFileTokens counts tokens of local file (transferred inline, nothing is uploaded).
+*/
+func countFileTokens(ctx context.Context, geminiModel *genai.GenerativeModel, filename, mimeType string) (int32, error) {
+	info, err := os.Stat(filename)
+	if err != nil {
+		return 0, err
+	}
+	if info.Size() > maxInlineTokenCountSize {
+		return 0, fmt.Errorf("file too large for token counting")
+	}
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return 0, err
+	}
+
+	resp, err := geminiModel.CountTokens(ctx, genai.Blob{MIMEType: mimeType, Data: data})
+	if err != nil {
+		return 0, err
+	}
+	return resp.TotalTokens, nil
+}
+
+/*
+countTextTokens counts tokens of text (e.g. converted document).
+*/
+func countTextTokens(ctx context.Context, geminiModel *genai.GenerativeModel, text string) (int32, error) {
+	resp, err := geminiModel.CountTokens(ctx, genai.Text(text))
+	if err != nil {
+		return 0, err
+	}
+	return resp.TotalTokens, nil
+}
+
+/*
+printFileTokens counts and prints tokens of each resolved file (converted documents as markdown).
+The content of each file is sent inline to Gemini for counting (not uploaded as remote file).
+*/
+func printFileTokens(allFiles []string, convertedFiles map[string]string) {
+	ctx := context.Background()
+	client, err := createClient(ctx)
+	if err != nil {
+		fmt.Printf("error [%v] creating AI client\n", err)
+		return
+	}
+	defer client.Close()
+	geminiModel := client.GenerativeModel(progConfig.GeminiAiModel)
+
+	fmt.Printf("\nTokens of files (content sent to Gemini for counting):\n")
+	totalTokens := int32(0)
+	for _, file := range allFiles {
+		var count int32
+		if markdown, ok := convertedFiles[file]; ok {
+			count, err = countTextTokens(ctx, geminiModel, markdown)
+		} else if _, isConverted := findDocumentConverter(file); isConverted {
+			continue // conversion failed
+		} else {
+			var mimeType string
+			mimeType, err = getMimeType(file)
+			if err != nil {
+				continue
+			}
+			count, err = countFileTokens(ctx, geminiModel, file, mimeType)
+		}
+		tokens := "-"
+		if err == nil {
+			tokens = fmt.Sprintf("%d", count)
+			totalTokens += count
+		}
+		fmt.Printf("  %10s  %s\n", tokens, file)
+	}
+	fmt.Printf("\nTotal tokens: %d (files which could not be counted are not included)\n", totalTokens)
+}
+
+/*
+tokenLimitUsage calculates share of input token limit (in percent).
+*/
+func tokenLimitUsage(tokens int32) float64 {
+	if modelInfo == nil || modelInfo.InputTokenLimit == 0 {
+		return 0.0
+	}
+	return float64(tokens) / float64(modelInfo.InputTokenLimit) * 100.0
+}
+
+/*
+checkTokenLimit checks prompt tokens against input token limit and applies configured policy.
+Returns true if prompt should be sent to AI model (policy 'ask' reads the answer from terminal, queued prompts are kept).
+*/
+func checkTokenLimit(tokens int32) bool {
+	usage := tokenLimitUsage(tokens)
+	fmt.Printf("Prompt tokens: %d of %d (%.1f%% of input token limit)\n", tokens, modelInfo.InputTokenLimit, usage)
+
+	if usage < float64(progConfig.GeminiTokenLimitThreshold) {
+		return true
+	}
+
+	switch progConfig.GeminiTokenLimitPolicy {
+	case "warn":
+		fmt.Printf("warning: prompt exceeds token limit threshold (%d%%)\n", progConfig.GeminiTokenLimitThreshold)
+		return true
+	case "ask":
+		question := fmt.Sprintf("Prompt exceeds token limit threshold (%d%%). Send anyway?", progConfig.GeminiTokenLimitThreshold)
+		return askForConfirmation(question)
+	case "refuse":
+		fmt.Printf("error: prompt exceeds token limit threshold (%d%%), prompt not sent\n", progConfig.GeminiTokenLimitThreshold)
+		return false
+	}
+	return true
+}
+
+/*
+askForConfirmation asks user for confirmation, answer is read from terminal only (prompts of other input
+channels, e.g. localhost, can't confirm). Answers other than yes or no are rejected and the question is repeated.
+*/
+func askForConfirmation(question string) bool {
+	if !progConfig.InputFromTerminal {
+		fmt.Printf("%s Not confirmed (confirmation requires input from terminal).\n", question)
+		return false
+	}
+
+	confirmationPending.Store(true)
+	defer confirmationPending.Store(false)
+	fmt.Printf("%s Answer 'yes' or 'no': ", question)
+	for {
+		answer := strings.ToLower(strings.TrimSpace(<-confirmationChannel))
+		switch answer {
+		case "yes", "y":
+			return true
+		case "no", "n":
+			fmt.Printf("Not confirmed.\n")
+			return false
+		}
+		fmt.Printf("Answer [%s] rejected, answer 'yes' or 'no': ", answer)
+	}
+}
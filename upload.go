@@ -0,0 +1,234 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aquilax/truncate"
+	"github.com/google/generative-ai-go/genai"
+	"golang.org/x/term"
+)
+
+// UploadProgress represents upload and processing state of all files (shown as in-place updated table)
+type UploadProgress struct {
+	mu       sync.Mutex
+	names    []string
+	statuses []string
+	printed  bool
+	inPlace  bool // table fits into terminal (otherwise table is printed once when finished)
+}
+
+// interval for polling state of remote files in processing
+const fileStatePollingInterval = 3 * time.Second
+
+/*
+uploadFilesToGemini uploads all files given from command line (bounded concurrency, concurrent state polling).
+GeminiMaxWaitTimeFileProcessing is an overall deadline (since start of uploads) for processing of remote files.
+*/
+func uploadFilesToGemini(ctx context.Context, client *genai.Client, clFiles []string) ([]*genai.File, error) {
+	files := []*genai.File{}
+	if len(clFiles) == 0 {
+		return files, nil
+	}
+	deadline := time.Now().Add(time.Duration(progConfig.GeminiMaxWaitTimeFileProcessing) * time.Second)
+
+	// remote files which can be reused (upload cache)
+	remoteFiles := map[string]*genai.File{}
+	if uploadCache != nil && len(uploadCache.Entries) > 0 {
+		var err error
+		remoteFiles, err = listRemoteFiles(ctx, client)
+		if err != nil {
+			fmt.Printf("error [%v] listing remote files\n", err)
+			remoteFiles = nil
+		}
+	}
+
+	fmt.Printf("\nFile uploads:\n")
+	progress := newUploadProgress(clFiles)
+	if progress.inPlace {
+		progress.print()
+	}
+	stopRefresh := make(chan bool)
+	refreshDone := make(chan bool)
+	go func() {
+		ticker := time.NewTicker(500 * time.Millisecond)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-stopRefresh:
+				progress.print()
+				refreshDone <- true
+				return
+			case <-ticker.C:
+				if progress.inPlace {
+					progress.print()
+				}
+			}
+		}
+	}()
+
+	// upload and poll files concurrently (number of concurrent uploads is limited)
+	results := make([]*genai.File, len(clFiles))
+	semaphore := make(chan bool, progConfig.GeminiUploadConcurrency)
+	var wg sync.WaitGroup
+	for i, filename := range clFiles {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			semaphore <- true
+			file, info := uploadOneFile(ctx, client, filename, remoteFiles, func(status string) { progress.set(i, status) })
+			<-semaphore
+			if file != nil {
+				file = waitForFileProcessing(ctx, client, file, deadline, func(status string) { progress.set(i, info+", "+status) })
+			}
+			results[i] = file
+		}()
+	}
+	wg.Wait()
+	stopRefresh <- true
+	<-refreshDone
+
+	if uploadCache != nil {
+		uploadCache.save()
+	}
+	for _, file := range results {
+		if file != nil {
+			files = append(files, file)
+		}
+	}
+
+	return files, nil
+}
+
+/*
+uploadOneFile uploads local file or reuses remote file with same content (upload cache).
+Returns remote file (nil if file couldn't be uploaded) and upload information.
+*/
+func uploadOneFile(ctx context.Context, client *genai.Client, filename string, remoteFiles map[string]*genai.File, setStatus func(string)) (*genai.File, string) {
+	if !fileExists(filename) {
+		setStatus("error: file does't exist")
+		return nil, ""
+	}
+
+	// reuse remote file with same content
+	hash := ""
+	if uploadCache != nil {
+		var err error
+		hash, err = hashFile(filename)
+		if err != nil {
+			setStatus(fmt.Sprintf("error [%v] hashing file", err))
+		}
+		if remoteFile, ok := uploadCache.lookup(hash, remoteFiles); ok && hash != "" {
+			reusedFile := *remoteFile
+			reusedFile.DisplayName = filename
+			info := fmt.Sprintf("reused (%.1f KiB, %s, expires %s)", float64(reusedFile.SizeBytes)/1024.0, reusedFile.MIMEType,
+				reusedFile.ExpirationTime.Local().Format("2006-01-02 15:04"))
+			setStatus(info)
+			return &reusedFile, info
+		}
+	}
+
+	// display name = max 512 characters
+	uploadOptions := genai.UploadFileOptions{}
+	uploadOptions.DisplayName = filename
+	if mimeType, ok := progConfig.UploadMimeTypes[strings.ToLower(filepath.Ext(filename))]; ok {
+		uploadOptions.MIMEType = mimeType
+	}
+
+	setStatus("uploading ...")
+	var file *genai.File
+	_, err := withRetry(ctx, "uploading file", func() error {
+		var err error
+		file, err = uploadFile(ctx, client, filename, &uploadOptions)
+		return err
+	})
+	if err != nil {
+		setStatus(fmt.Sprintf("error: %v", err))
+		return nil, ""
+	}
+
+	info := fmt.Sprintf("uploaded (%.1f KiB, %s)", float64(file.SizeBytes)/1024.0, file.MIMEType)
+	setStatus(info)
+	if uploadCache != nil && hash != "" {
+		uploadCache.add(hash, filename, file)
+	}
+	return file, info
+}
+
+/*
+waitForFileProcessing polls state of remote file until it is no longer processing or deadline is reached
+(e.g. videos need to be processed). Returns remote file with last known state.
+*/
+func waitForFileProcessing(ctx context.Context, client *genai.Client, file *genai.File, deadline time.Time, setStatus func(string)) *genai.File {
+	start := time.Now()
+	for file.State == genai.FileStateProcessing {
+		if time.Now().After(deadline) {
+			setStatus(fmt.Sprintf("%s (deadline reached after %.0f secs)", file.State.String(), time.Since(start).Seconds()))
+			return file
+		}
+		setStatus(fmt.Sprintf("%s (%.0f secs)", file.State.String(), time.Since(start).Seconds()))
+		time.Sleep(fileStatePollingInterval)
+
+		_, err := withRetry(ctx, "getting file state", func() error {
+			remoteFile, err := client.GetFile(ctx, file.Name)
+			if err == nil {
+				remoteFile.DisplayName = file.DisplayName
+				file = remoteFile
+			}
+			return err
+		})
+		if err != nil {
+			setStatus(fmt.Sprintf("error [%v] getting state", err))
+			return file
+		}
+	}
+
+	setStatus(file.State.String())
+	return file
+}
+
+/*
+newUploadProgress creates progress table for given files.
+*/
+func newUploadProgress(filenames []string) *UploadProgress {
+	progress := &UploadProgress{names: filenames, statuses: make([]string, len(filenames))}
+	_, terminalHeight, err := term.GetSize(int(os.Stdout.Fd()))
+	progress.inPlace = err == nil && len(filenames) < terminalHeight-2
+	for i := range progress.statuses {
+		progress.statuses[i] = "waiting"
+	}
+	return progress
+}
+
+/*
+set sets status of i-th file.
+*/
+func (up *UploadProgress) set(i int, status string) {
+	up.mu.Lock()
+	defer up.mu.Unlock()
+	up.statuses[i] = status
+}
+
+/*
+print prints progress table (table printed before is overwritten in place).
+*/
+func (up *UploadProgress) print() {
+	up.mu.Lock()
+	defer up.mu.Unlock()
+
+	var table strings.Builder
+	if up.printed {
+		table.WriteString(fmt.Sprintf("\033[%dA", len(up.names)))
+	}
+	for i, name := range up.names {
+		name = truncate.Truncate(name, 60, "...", truncate.PositionMiddle)
+		table.WriteString(fmt.Sprintf("\r\033[K  %-60s  %s\n", name, up.statuses[i]))
+	}
+	fmt.Print(table.String())
+	up.printed = true
+}
@@ -0,0 +1,176 @@
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/google/generative-ai-go/genai"
+	"google.golang.org/api/iterator"
+)
+
+// UploadCacheEntry represents a remote file uploaded for a local file content
+type UploadCacheEntry struct {
+	Hash           string    `json:"hash"`
+	Filename       string    `json:"filename"`
+	RemoteName     string    `json:"remoteName"`
+	URI            string    `json:"uri"`
+	MIMEType       string    `json:"mimeType"`
+	SizeBytes      int64     `json:"sizeBytes"`
+	Uploaded       time.Time `json:"uploaded"`
+	ExpirationTime time.Time `json:"expirationTime"`
+}
+
+// UploadCache maps content hashes (sha256) of local files to remote files
+type UploadCache struct {
+	mu      sync.Mutex
+	Entries map[string]UploadCacheEntry `json:"entries"`
+}
+
+// upload cache (nil = upload cache disabled)
+var uploadCache *UploadCache
+
+// remote files expiring within this margin are uploaded again
+const uploadCacheExpiryMargin = time.Hour
+
+/*
+loadUploadCache loads upload cache (empty cache if cache file doesn't exist).
+*/
+func loadUploadCache() *UploadCache {
+	cache := &UploadCache{Entries: map[string]UploadCacheEntry{}}
+	if !fileExists(progConfig.UploadCacheFile) {
+		return cache
+	}
+
+	data, err := os.ReadFile(progConfig.UploadCacheFile)
+	if err != nil {
+		fmt.Printf("error [%v] reading upload cache\n", err)
+		return cache
+	}
+	err = json.Unmarshal(data, cache)
+	if err != nil {
+		fmt.Printf("error [%v] unmarshalling upload cache\n", err)
+	}
+	if cache.Entries == nil {
+		cache.Entries = map[string]UploadCacheEntry{}
+	}
+	return cache
+}
+
+/*
+save writes upload cache to cache file.
+*/
+func (uc *UploadCache) save() {
+	uc.mu.Lock()
+	defer uc.mu.Unlock()
+
+	data, err := json.MarshalIndent(uc, "", "  ")
+	if err != nil {
+		fmt.Printf("error [%v] marshalling upload cache\n", err)
+		return
+	}
+	err = os.WriteFile(progConfig.UploadCacheFile, data, 0644)
+	if err != nil {
+		fmt.Printf("error [%v] writing upload cache\n", err)
+	}
+}
+
+/*
+lookup gets reusable remote file for content hash (remote file must exist, not be failed and not expire soon).
+Without list of remote files (nil), nothing can be reused.
+*/
+func (uc *UploadCache) lookup(hash string, remoteFiles map[string]*genai.File) (*genai.File, bool) {
+	uc.mu.Lock()
+	defer uc.mu.Unlock()
+
+	entry, ok := uc.Entries[hash]
+	if !ok || remoteFiles == nil {
+		return nil, false
+	}
+	remoteFile, ok := remoteFiles[entry.RemoteName]
+	if !ok || remoteFile.State == genai.FileStateFailed {
+		delete(uc.Entries, hash)
+		return nil, false
+	}
+	if !remoteFile.ExpirationTime.IsZero() && time.Until(remoteFile.ExpirationTime) < uploadCacheExpiryMargin {
+		delete(uc.Entries, hash)
+		return nil, false
+	}
+	return remoteFile, true
+}
+
+/*
+add adds uploaded remote file for content hash to upload cache.
+*/
+func (uc *UploadCache) add(hash, filename string, file *genai.File) {
+	uc.mu.Lock()
+	defer uc.mu.Unlock()
+
+	uc.Entries[hash] = UploadCacheEntry{
+		Hash:           hash,
+		Filename:       filename,
+		RemoteName:     file.Name,
+		URI:            file.URI,
+		MIMEType:       file.MIMEType,
+		SizeBytes:      file.SizeBytes,
+		Uploaded:       time.Now(),
+		ExpirationTime: file.ExpirationTime,
+	}
+}
+
+/*
+remove removes all entries referencing given remote file from upload cache.
+*/
+func (uc *UploadCache) remove(remoteName string) {
+	uc.mu.Lock()
+	defer uc.mu.Unlock()
+
+	for hash, entry := range uc.Entries {
+		if entry.RemoteName == remoteName {
+			delete(uc.Entries, hash)
+		}
+	}
+}
+
+/*
+listRemoteFiles lists all remote files (key is remote file name, e.g. 'files/abc-123').
+*/
+func listRemoteFiles(ctx context.Context, client *genai.Client) (map[string]*genai.File, error) {
+	remoteFiles := map[string]*genai.File{}
+	iter := client.ListFiles(ctx)
+	for {
+		file, err := iter.Next()
+		if err == iterator.Done {
+			break
+		}
+		if err != nil {
+			return remoteFiles, err
+		}
+		remoteFiles[file.Name] = file
+	}
+	return remoteFiles, nil
+}
+
+/*
+hashFile calculates content hash (sha256) of local file.
+*/
+func hashFile(filename string) (string, error) {
+	file, err := os.Open(filename)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+
+	hash := sha256.New()
+	_, err = io.Copy(hash, file)
+	if err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
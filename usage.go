@@ -17,14 +17,23 @@ printUsage prints the usage of this program.
 */
 func printUsage() {
 	fmt.Printf("\nUsage:\n")
-	fmt.Printf("  %s [options] [files]\n", progName)
+	fmt.Printf("  %s [options] [files, directories, globs]\n", progName)
 
 	fmt.Printf("\nExamples:\n")
 	fmt.Printf("  %s\n", progName)
 	fmt.Printf("  %s -candidates 2\n", progName)
 	fmt.Printf("  %s -temperature 1.8\n", progName)
+	fmt.Printf("  %s -chat\n", progName)
+	fmt.Printf("  %s -stream\n", progName)
+	fmt.Printf("  %s -session ganymed-review\n", progName)
 	fmt.Printf("  %s *.go README.md\n", progName)
+	fmt.Printf("  %s ./ganymed 'docs/**/*.md'\n", progName)
 	fmt.Printf("  %s -dryrun -uploads ganymed-project-files.txt\n", progName)
+	fmt.Printf("  %s -dryrun -tokens ./ganymed\n", progName)
+	fmt.Printf("  %s -usage\n", progName)
+	fmt.Printf("  %s -files list\n", progName)
+	fmt.Printf("  %s -files delete 'files/abc-*'\n", progName)
+	fmt.Printf("  %s -extract history-markdown/*.md\n", progName)
 
 	fmt.Printf("\nOptions:\n")
 	flag.PrintDefaults()
@@ -36,9 +45,45 @@ func printUsage() {
 	fmt.Printf("    Terminal, File, localhost\n")
 	fmt.Printf("  - Output is available in the following formats:\n")
 	fmt.Printf("    Markdown (Editor), HTML (Browser), Ansi (Terminal)\n")
-	fmt.Printf("  - Each prompt is self-contained (no chat).\n")
+	fmt.Printf("  - Each prompt is self-contained, unless chat mode is enabled.\n")
+	fmt.Printf("  - In chat mode, prompts keep the context of the conversation.\n")
+	fmt.Printf("  - Chat sessions are saved and can be resumed or forked later (generation\n")
+	fmt.Printf("    parameters of session apply, command line options take precedence).\n")
+	fmt.Printf("  - Commands (e.g. '/new', '/help') can be given via all input channels.\n")
+	fmt.Printf("  - Files can be attached and detached at runtime ('/attach', '/detach',\n")
+	fmt.Printf("    '/attachments' or localhost endpoints with same names, cross-origin\n")
+	fmt.Printf("    requests from web pages are refused).\n")
 	fmt.Printf("  - Specified files are transmitted to 'Google Gemini AI',\n")
 	fmt.Printf("    allowing prompts to reference their contents.\n")
+	fmt.Printf("  - Directories are uploaded recursively (respecting .gitignore files).\n")
+	fmt.Printf("  - Unchanged files are not uploaded again (upload cache).\n")
+	fmt.Printf("  - Small and text-like files are embedded directly into the prompt (no upload).\n")
+	fmt.Printf("  - Documents (.docx, .odt, .xlsx, .pptx, .ipynb, .epub) are converted\n")
+	fmt.Printf("    locally into markdown before upload.\n")
+	fmt.Printf("  - Attached files are watched: changed or expiring files are re-uploaded.\n")
+	fmt.Printf("  - Attached files can be put into a context cache (reduced cost for many\n")
+	fmt.Printf("    prompts against the same files).\n")
+	fmt.Printf("  - Functions declared in the configuration (commands, HTTP endpoints) can be\n")
+	fmt.Printf("    called by the AI model (optionally after confirmation).\n")
+	fmt.Printf("  - Code execution lets the AI model run Python code (e.g. to verify\n")
+	fmt.Printf("    calculations), executed code and results are shown in the response.\n")
+	fmt.Printf("  - Directives in the prompt text ('@file:', '@glob:', '@cmd:') reference\n")
+	fmt.Printf("    data for this prompt only.\n")
+	fmt.Printf("  - Safety thresholds are configurable per harm category, blocked prompts\n")
+	fmt.Printf("    and responses are explained (incl. safety ratings).\n")
+	fmt.Printf("  - Inline data of responses (e.g. generated images) is saved as files\n")
+	fmt.Printf("    in an attachments directory next to the history files.\n")
+	fmt.Printf("  - A JSON schema (configuration or '@schema:' directive) requests a JSON\n")
+	fmt.Printf("    response, which is validated and saved to the JSON history.\n")
+	fmt.Printf("  - Option '-files' manages remote files (e.g. 'purge' deletes all files\n")
+	fmt.Printf("    uploaded by this program, useful after a crash).\n")
+	fmt.Printf("  - Option '-extract' writes fenced code blocks of responses in markdown\n")
+	fmt.Printf("    files to an output directory (also available for each new response).\n")
+	fmt.Printf("  - Option '-dryrun' lists the resolved files without contacting Gemini.\n")
+	fmt.Printf("    With '-tokens', the content of each file is sent to Gemini for token\n")
+	fmt.Printf("    counting (inline, no remote file is created).\n")
+	fmt.Printf("  - Transient errors (e.g. quota, overloaded) are retried with backoff,\n")
+	fmt.Printf("    then the prompt is sent to the configured fallback models.\n")
 	fmt.Printf("  - The program offers many configuration options.\n")
 	fmt.Printf("  - The presentation of the outputs can be customized.\n")
 
@@ -70,8 +115,9 @@ func printUsage() {
 
 	fmt.Printf("\nTip:\n")
 	fmt.Printf("  In practice, a browser is useful for both creating prompts and presenting\n")
-	fmt.Printf("  the output. The simple 'prompt-input.html' webpage can be used for creating\n")
-	fmt.Printf("  and sending prompts to 'localhost'.\n")
+	fmt.Printf("  the output. The simple 'prompt-input.html' webpage (http://localhost:port/) can\n")
+	fmt.Printf("  be used for creating and sending prompts. With 'HTMLLiveView' enabled, all responses\n")
+	fmt.Printf("  are shown on one self-updating page (http://localhost:port/live).\n")
 
 	fmt.Printf("\n")
 	os.Exit(1)
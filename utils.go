@@ -179,6 +179,11 @@ func slurpFile(filename string) ([]string, error) {
 getMimeType gets mime type for given file.
 */
 func getMimeType(filename string) (string, error) {
+	// configured mime type for file extension (e.g. '.go': 'text/plain')
+	if mimeType, ok := progConfig.UploadMimeTypes[strings.ToLower(filepath.Ext(filename))]; ok {
+		return mimeType, nil
+	}
+
 	file, err := os.Open(filename)
 	if err != nil {
 		return "", err
@@ -0,0 +1,253 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sync/atomic"
+	"time"
+
+	"github.com/google/generative-ai-go/genai"
+)
+
+// FileSnapshot represents state of local file at upload time (used to detect local changes)
+type FileSnapshot struct {
+	ModTime time.Time
+	Size    int64
+}
+
+// snapshots of attached local files (source documents for converted files), protected by uploadedFilesMu
+var fileSnapshots = map[string]FileSnapshot{}
+
+// sync signaled by file watcher but not yet handled
+var syncPending atomic.Bool
+
+// sync signals of file watcher (separate from prompt channel, which also answers confirmation questions)
+var syncChannel = make(chan struct{}, 1)
+
+/*
+takeFileSnapshot gets current state of local file.
+*/
+func takeFileSnapshot(filename string) (FileSnapshot, error) {
+	info, err := os.Stat(filename)
+	if err != nil {
+		return FileSnapshot{}, err
+	}
+	return FileSnapshot{ModTime: info.ModTime(), Size: info.Size()}, nil
+}
+
+/*
+watchFiles records current state of given local files (baseline for change detection).
+*/
+func watchFiles(filenames []string) {
+	uploadedFilesMu.Lock()
+	defer uploadedFilesMu.Unlock()
+
+	for _, filename := range filenames {
+		snapshot, err := takeFileSnapshot(filename)
+		if err == nil {
+			fileSnapshots[filename] = snapshot
+		}
+	}
+}
+
+/*
+attachedFileChanged checks if local file changed since upload (first check only records its state).
+Caller must hold uploadedFilesMu.
+*/
+func attachedFileChanged(filename string) bool {
+	snapshot, err := takeFileSnapshot(filename)
+	if err != nil {
+		// deleted local files are kept as they are
+		return false
+	}
+	known, ok := fileSnapshots[filename]
+	if !ok {
+		fileSnapshots[filename] = snapshot
+		return false
+	}
+	return !snapshot.ModTime.Equal(known.ModTime) || snapshot.Size != known.Size
+}
+
+/*
+attachedFileExpiring checks if remote file expires within UploadRefreshBeforeExpiry.
+*/
+func attachedFileExpiring(file *genai.File) bool {
+	if file.ExpirationTime.IsZero() {
+		return false
+	}
+	margin := time.Duration(progConfig.UploadRefreshBeforeExpiry) * time.Minute
+	return time.Until(file.ExpirationTime) < margin
+}
+
+/*
+outdatedAttachedFiles gets attached files which have been changed locally or expire soon.
+*/
+func outdatedAttachedFiles() ([]*genai.File, []*InlineFile) {
+	uploadedFilesMu.Lock()
+	defer uploadedFilesMu.Unlock()
+
+	files := []*genai.File{}
+	for _, uploadedFile := range uploadedFiles {
+		if attachedFileChanged(documentSource(uploadedFile.DisplayName)) || attachedFileExpiring(uploadedFile) {
+			files = append(files, uploadedFile)
+		}
+	}
+	inlineFiles := []*InlineFile{}
+	for _, inlinedFile := range inlinedFiles {
+		if attachedFileChanged(documentSource(inlinedFile.Filename)) {
+			inlineFiles = append(inlineFiles, inlinedFile)
+		}
+	}
+	return files, inlineFiles
+}
+
+/*
+startFileWatcher periodically checks attached files and signals main loop (sync channel) if files are outdated.
+*/
+func startFileWatcher() {
+	ticker := time.NewTicker(time.Duration(progConfig.UploadWatchInterval) * time.Second)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		if syncPending.Load() {
+			continue
+		}
+		files, inlineFiles := outdatedAttachedFiles()
+		if len(files) == 0 && len(inlineFiles) == 0 {
+			continue
+		}
+		syncPending.Store(true)
+		select {
+		case syncChannel <- struct{}{}:
+		default:
+		}
+	}
+}
+
+/*
+syncAttachedFiles re-uploads (or re-reads) attached files which have been changed locally or expire soon
+and swaps them in attachment set. Remote files of old versions are deleted unless still used by a chat session.
+*/
+func syncAttachedFiles(ctx context.Context, client *genai.Client, geminiModel *genai.GenerativeModel) {
+	syncPending.Store(false)
+
+	files, inlineFiles := outdatedAttachedFiles()
+	if len(files) == 0 && len(inlineFiles) == 0 {
+		return
+	}
+	fmt.Printf("\nSyncing %d attached %s (changed locally or expiring) ...\n", len(files)+len(inlineFiles),
+		pluralize(len(files)+len(inlineFiles), "file"))
+
+	for _, file := range files {
+		source := documentSource(file.DisplayName)
+		uploadedFilesMu.Lock()
+		changed := attachedFileChanged(source)
+		uploadedFilesMu.Unlock()
+		if uploadCache != nil {
+			// prevents reuse of expiring remote file
+			uploadCache.remove(file.Name)
+		}
+		newFiles, newInlineFiles, ok := reloadAttachedFile(ctx, client, source)
+		if !ok {
+			continue
+		}
+
+		// expiring files with unchanged content replace old references in chat history (avoids broken chat)
+		if !changed && len(newFiles) == 1 && chatSession != nil {
+			replaceHistoryFileURI(file.URI, newFiles[0].URI)
+		}
+		replaceAttachedFile(file, nil, newFiles, newInlineFiles)
+
+		if user, inUse := remoteFileUser(file); inUse {
+			fmt.Printf("file [%s] synced (old remote file kept, still used by %s)\n", file.DisplayName, user)
+			continue
+		}
+		err := client.DeleteFile(ctx, file.Name)
+		if err != nil {
+			fmt.Printf("error [%v] deleting old remote file [%s]\n", err, file.DisplayName)
+		} else {
+			fmt.Printf("file [%s] synced (old remote file deleted)\n", file.DisplayName)
+		}
+	}
+
+	for _, inlineFile := range inlineFiles {
+		newFiles, newInlineFiles, ok := reloadAttachedFile(ctx, client, documentSource(inlineFile.Filename))
+		if !ok {
+			continue
+		}
+		replaceAttachedFile(nil, inlineFile, newFiles, newInlineFiles)
+		fmt.Printf("file [%s] synced\n", inlineFile.Filename)
+	}
+
+	if uploadCache != nil {
+		uploadCache.save()
+	}
+	updateContextCache(ctx, client, geminiModel)
+	saveAttachmentSet(geminiModel)
+}
+
+/*
+reloadAttachedFile converts, embeds or uploads current version of local file.
+*/
+func reloadAttachedFile(ctx context.Context, client *genai.Client, filename string) ([]*genai.File, []*InlineFile, bool) {
+	snapshot, err := takeFileSnapshot(filename)
+	if err != nil {
+		fmt.Printf("error [%v] reading file [%s], old version kept\n", err, filename)
+		return nil, nil, false
+	}
+	newFiles, newInlineFiles, err := prepareFiles(ctx, client, []string{filename})
+	if err != nil || len(newFiles)+len(newInlineFiles) == 0 {
+		fmt.Printf("error [%v] uploading file [%s], old version kept\n", err, filename)
+		return nil, nil, false
+	}
+
+	uploadedFilesMu.Lock()
+	fileSnapshots[filename] = snapshot
+	uploadedFilesMu.Unlock()
+	return newFiles, newInlineFiles, true
+}
+
+/*
+replaceAttachedFile replaces attached file (uploaded or inlined) by its new version (position is kept if kind is unchanged).
+*/
+func replaceAttachedFile(oldFile *genai.File, oldInlineFile *InlineFile, newFiles []*genai.File, newInlineFiles []*InlineFile) {
+	uploadedFilesMu.Lock()
+	defer uploadedFilesMu.Unlock()
+
+	files := []*genai.File{}
+	for _, uploadedFile := range uploadedFiles {
+		if uploadedFile != oldFile {
+			files = append(files, uploadedFile)
+			continue
+		}
+		files = append(files, newFiles...)
+		newFiles = nil
+	}
+	uploadedFiles = append(files, newFiles...)
+
+	inlineFiles := []*InlineFile{}
+	for _, inlinedFile := range inlinedFiles {
+		if inlinedFile != oldInlineFile {
+			inlineFiles = append(inlineFiles, inlinedFile)
+			continue
+		}
+		inlineFiles = append(inlineFiles, newInlineFiles...)
+		newInlineFiles = nil
+	}
+	inlinedFiles = append(inlineFiles, newInlineFiles...)
+}
+
+/*
+replaceHistoryFileURI replaces references to remote file in current chat history.
+*/
+func replaceHistoryFileURI(oldURI, newURI string) {
+	for _, content := range chatSession.History {
+		for i, part := range content.Parts {
+			if fileData, ok := part.(genai.FileData); ok && fileData.URI == oldURI {
+				fileData.URI = newURI
+				content.Parts[i] = fileData
+			}
+		}
+	}
+}